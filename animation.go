@@ -0,0 +1,323 @@
+package main
+
+import (
+	"math"
+
+	"github.com/jakecoffman/cp"
+
+	"github.com/unitoftime/flow/asset"
+	"github.com/unitoftime/glitch"
+
+	"github.com/unitoftime/boxlin/aseprite"
+)
+
+// Loads the animation data that the asset pipeline writes next to the spritesheet, indexed by aseprite file name
+func LoadAnimations(load *asset.Load, filepath string) (map[string]aseprite.Animation, error) {
+	animations := make(map[string]aseprite.Animation)
+	err := load.Json(filepath, &animations)
+	if err != nil {
+		return nil, err
+	}
+	return animations, nil
+}
+
+// Returns the border of a frame's 9-slice in the form that GetNinePanel expects, or fallback if the frame doesn't have one. The slice is measured against the frame as it was exported, so trimming is accounted for
+func NinePanelBorder(anim aseprite.Animation, frame int, size glitch.Vec2, fallback glitch.Rect) glitch.Rect {
+	if frame >= len(anim.Frames) { return fallback }
+	offset := anim.Frames[frame].Offset
+
+	for _, slice := range anim.Slices {
+		key, ok := slice.Key(frame)
+		if !ok || key.Center.Empty() { continue }
+
+		// The center is relative to the slice, so move it onto the canvas and then onto the exported frame
+		center := key.Center.Add(key.Bounds.Min).Sub(offset)
+		left := float64(center.Min.X)
+		right := size[0] - float64(center.Max.X)
+		// Aseprite's y axis points down, so its top is glitch's max
+		top := float64(center.Min.Y)
+		bottom := size[1] - float64(center.Max.Y)
+		return glitch.R(left, bottom, right, top)
+	}
+	return fallback
+}
+
+// The sprite that walls, bin dividers and buttons are all cut from
+const wallSprite = "wall-0.png"
+
+// The sprite drawn behind everything in the level
+const backgroundSprite = "background-0.png"
+
+// How hard a package has to hit something to shake the camera or cause a hit-stop, as a change in speed
+const (
+	shakeImpact = 40.0
+	fullShakeImpact = 200.0 // The impact past shakeImpact that adds full trauma
+	hitStopImpact = 120.0
+	hitStopDuration = 0.08 // In seconds
+)
+
+// Returns the wall nine panel, using the wall's 9-slice if it has one
+func (g *Game) WallPanel() (*glitch.NinePanelSprite, error) {
+	sprite, err := g.spritesheet.Get(wallSprite)
+	if err != nil {
+		return nil, err
+	}
+	bounds := sprite.Bounds()
+	border := NinePanelBorder(g.animations["wall"], 0, glitch.Vec2{bounds.W(), bounds.H()}, glitch.R(8, 8, 8, 8))
+	return g.spritesheet.GetNinePanel(wallSprite, border)
+}
+
+// One frame of a sprite animation. Frames without a sprite keep drawing the body's own sprite, which lets a squash and stretch be shared between sprites. The stretch is applied on top of how the sprite would normally be drawn, so the zero value changes nothing
+type AnimationFrame struct {
+	sprite *glitch.Sprite
+	duration float64 // In seconds
+	stretch glitch.Vec2 // Added to the scale along each axis
+}
+
+// A list of frames along with tags that name ranges of them, in the same form that aseprite uses
+type SpriteAnimation struct {
+	frames []AnimationFrame
+	tags []aseprite.Tag
+}
+
+// Looks up the sprites for each exported aseprite frame
+func NewSpriteAnimation(spritesheet *asset.Spritesheet, anim aseprite.Animation) (*SpriteAnimation, error) {
+	frames := make([]AnimationFrame, len(anim.Frames))
+	for i, frame := range anim.Frames {
+		sprite, err := spritesheet.Get(frame.Sprite)
+		if err != nil {
+			return nil, err
+		}
+		frames[i] = AnimationFrame{
+			sprite: sprite,
+			duration: float64(frame.Duration) / 1000,
+		}
+	}
+	return &SpriteAnimation{
+		frames: frames,
+		tags: anim.Tags,
+	}, nil
+}
+
+// Turns each exported aseprite frame into a stretch of whatever sprite is being drawn, by how its size compares to the first frame. The frames' own sprites are never drawn
+func NewStretchAnimation(spritesheet *asset.Spritesheet, anim aseprite.Animation) (*SpriteAnimation, error) {
+	a, err := NewSpriteAnimation(spritesheet, anim)
+	if err != nil {
+		return nil, err
+	}
+	if len(a.frames) == 0 {
+		return a, nil
+	}
+
+	base := a.frames[0].sprite.Bounds()
+	for i := range a.frames {
+		bounds := a.frames[i].sprite.Bounds()
+		a.frames[i].stretch = glitch.Vec2{bounds.W() / base.W() - 1, bounds.H() / base.H() - 1}
+		a.frames[i].sprite = nil
+	}
+	return a, nil
+}
+
+// Returns the named tag. An empty name is the whole animation looping forwards
+func (a *SpriteAnimation) Tag(name string) (aseprite.Tag, bool) {
+	if name == "" {
+		return aseprite.Tag{To: len(a.frames) - 1}, len(a.frames) > 0
+	}
+	for _, tag := range a.tags {
+		if tag.Name == name {
+			return tag, true
+		}
+	}
+	return aseprite.Tag{}, false
+}
+
+// The playback state of a sprite animation. Each animated body needs its own, but they can all share the same SpriteAnimation
+type AnimatedSprite struct {
+	anim *SpriteAnimation
+	idle string // The tag that is played once any other tag finishes
+	tag aseprite.Tag
+	frame int
+	step int // Either 1 or -1, depending on which way the tag is currently being played
+	remaining float64 // Seconds left on the current frame
+	plays int // How many times the tag has been played through. Each direction of a ping pong counts as one
+	done bool
+}
+
+// Starts playing the idle tag, or the whole animation if there is no tag with that name
+func NewAnimatedSprite(anim *SpriteAnimation, idle string) *AnimatedSprite {
+	if _, ok := anim.Tag(idle); !ok {
+		idle = ""
+	}
+	a := &AnimatedSprite{
+		anim: anim,
+		idle: idle,
+	}
+	a.Play(idle)
+	return a
+}
+
+// Restarts the animation from the start of the tag. Unknown tags are ignored
+func (a *AnimatedSprite) Play(name string) {
+	tag, ok := a.anim.Tag(name)
+	if !ok { return }
+
+	a.tag = tag
+	a.plays = 0
+	a.done = false
+	a.step = 1
+	a.frame = tag.From
+	if tag.Direction == aseprite.Reverse || tag.Direction == aseprite.PingPongReverse {
+		a.step = -1
+		a.frame = tag.To
+	}
+	a.remaining = a.anim.frames[a.frame].duration
+}
+
+// The name of the tag that is currently playing
+func (a *AnimatedSprite) Playing() string {
+	return a.tag.Name
+}
+
+// Returns true once a tag that doesn't loop forever has finished and there was no idle tag to go back to
+func (a *AnimatedSprite) Done() bool {
+	return a.done
+}
+
+// Returns the current frame, or the zero frame if the animation doesn't have any
+func (a *AnimatedSprite) Frame() AnimationFrame {
+	if len(a.anim.frames) == 0 { return AnimationFrame{} }
+	return a.anim.frames[a.frame]
+}
+
+// Moves the animation forward by dt seconds
+func (a *AnimatedSprite) Update(dt float64) {
+	if a.done || len(a.anim.frames) == 0 { return }
+
+	a.remaining -= dt
+	for a.remaining <= 0 && !a.done {
+		a.advance()
+
+		duration := a.anim.frames[a.frame].duration
+		if duration <= 0 {
+			// Frames without a duration would never let the loop finish
+			a.remaining = 0
+			break
+		}
+		a.remaining += duration
+	}
+}
+
+func (a *AnimatedSprite) advance() {
+	tag := a.tag
+	next := a.frame + a.step
+	if next >= tag.From && next <= tag.To {
+		a.frame = next
+		return
+	}
+
+	// Reached the end of the tag
+	a.plays++
+	if tag.Repeat > 0 && a.plays >= tag.Repeat {
+		if a.idle != tag.Name {
+			a.Play(a.idle)
+			return
+		}
+		a.done = true
+		return
+	}
+
+	switch tag.Direction {
+	case aseprite.PingPong, aseprite.PingPongReverse:
+		a.step = -a.step
+		if tag.From < tag.To {
+			a.frame += a.step
+		}
+	default:
+		if a.step > 0 {
+			a.frame = tag.From
+		} else {
+			a.frame = tag.To
+		}
+	}
+}
+
+// The aseprite files that the level's animations are exported from
+const (
+	packageAnimationName = "package-land"
+	packingLineAnimationName = "packing-line"
+)
+
+// Builds the peg, package and packing line animations from the exported aseprite tags. It has to be called again whenever the spritesheet or animations are reloaded
+func (g *Game) BuildAnimations() error {
+	pegAnimations := make(map[PegKind]*SpriteAnimation, len(pegTypes))
+	for kind, pegType := range pegTypes {
+		anim, err := NewSpriteAnimation(g.spritesheet, g.animations[pegType.animation])
+		if err != nil {
+			return err
+		}
+		pegAnimations[kind] = anim
+	}
+
+	packageAnimation, err := NewStretchAnimation(g.spritesheet, g.animations[packageAnimationName])
+	if err != nil {
+		return err
+	}
+	packingLineAnimation, err := NewSpriteAnimation(g.spritesheet, g.animations[packingLineAnimationName])
+	if err != nil {
+		return err
+	}
+
+	g.pegAnimations = pegAnimations
+	g.packageAnimation = packageAnimation
+	g.packingLineAnimation = packingLineAnimation
+	return nil
+}
+
+// Starts the contact animations, particles and camera shake for anything that touched something new during the last physics step. Arbiters keep their first contact state until the next step starts
+func (g *Game) PlayContactEffects() {
+	g.space.EachBody(func(body *cp.Body) {
+		sprite := body.UserData.(Sprite)
+		if sprite.anim == nil { return }
+
+		body.EachArbiter(func(arb *cp.Arbiter) {
+			if !arb.IsFirstContact() || arb.Count() == 0 { return }
+
+			// The arbiter lists the body it was reached from first, and its normal points away from that body
+			_, other := arb.Bodies()
+			contacts := arb.ContactPointSet()
+			point := contacts.Points[0].PointA
+			pos := glitch.Vec2{point.X, point.Y}
+			normal := contacts.Normal.ToAngle()
+
+			if sprite.peg != nil {
+				if other.UserData.(Sprite).isPackage {
+					sprite.anim.Play("hit")
+					g.particles.Emit(sparkEffect, pos, normal)
+				}
+			} else if sprite.isPackage {
+				// The change in speed from the impact, so that heavy and light packages shake the same for the same hit
+				impact := arb.TotalImpulse().Length() / body.Mass()
+				if impact > shakeImpact {
+					g.effects.AddTrauma((impact - shakeImpact) / fullShakeImpact)
+				}
+				if impact > hitStopImpact {
+					g.effects.HitStop(hitStopDuration)
+				}
+
+				if sprite.anim.Playing() != "land" {
+					sprite.anim.Play("land")
+					// The dust kicks back up off of whatever the package landed on
+					g.particles.Emit(dustEffect, pos, normal + math.Pi)
+				}
+			}
+		})
+	})
+}
+
+func (g *Game) UpdateAnimations(dt float64) {
+	g.space.EachBody(func(body *cp.Body) {
+		if anim := body.UserData.(Sprite).anim; anim != nil {
+			anim.Update(dt)
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package aseprite
+
+import (
+	"image"
+)
+
+// The parts of a file that are still needed at runtime once its frames have been exported into a spritesheet
+type Animation struct {
+	Frames []AnimationFrame `json:"frames"`
+	Tags []Tag `json:"tags"`
+	Slices []Slice `json:"slices"`
+}
+
+type AnimationFrame struct {
+	Sprite string `json:"sprite"` // The frame's name in the spritesheet
+	Duration int `json:"duration"` // In milliseconds
+	Offset image.Point `json:"offset"` // Where the exported frame's top left corner sits on the canvas, which is non zero when the frame was trimmed
+}
+
+// Builds the runtime data for the file. The name function returns the spritesheet name of each frame, and offsets holds how far each frame was trimmed, if at all
+func (file *File) Animation(name func(frame int) string, offsets []image.Point) Animation {
+	anim := Animation{
+		Frames: make([]AnimationFrame, len(file.Frames)),
+		Tags: file.Tags,
+		Slices: file.Slices,
+	}
+	for i, frame := range file.Frames {
+		anim.Frames[i] = AnimationFrame{
+			Sprite: name(i),
+			Duration: int(frame.Duration.Milliseconds()),
+		}
+		if i < len(offsets) {
+			anim.Frames[i].Offset = offsets[i]
+		}
+	}
+	return anim
+}
+
+func (a Animation) Tag(name string) (Tag, bool) {
+	for _, tag := range a.Tags {
+		if tag.Name == name {
+			return tag, true
+		}
+	}
+	return Tag{}, false
+}
+
+func (a Animation) Slice(name string) (Slice, bool) {
+	for _, slice := range a.Slices {
+		if slice.Name == name {
+			return slice, true
+		}
+	}
+	return Slice{}, false
+}
@@ -0,0 +1,546 @@
+// Package aseprite reads Aseprite .ase and .aseprite files.
+//
+// The format is documented at https://github.com/aseprite/aseprite/blob/main/docs/ase-file-specs.md
+package aseprite
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	headerMagic = 0xA5E0
+	frameMagic = 0xF1FA
+	headerSize = 128
+	frameHeaderSize = 16
+)
+
+// Chunk types
+const (
+	chunkOldPalette = 0x0004
+	chunkOldPalette2 = 0x0011
+	chunkLayer = 0x2004
+	chunkCel = 0x2005
+	chunkTags = 0x2018
+	chunkPalette = 0x2019
+	chunkSlice = 0x2022
+)
+
+// Cel types
+const (
+	celRaw = 0
+	celLinked = 1
+	celCompressed = 2
+)
+
+const (
+	LayerVisible = 1 << 0
+	LayerBackground = 1 << 3
+	LayerReference = 1 << 6
+)
+
+const (
+	LayerTypeNormal = 0
+	LayerTypeGroup = 1
+	LayerTypeTilemap = 2
+)
+
+type Layer struct {
+	Name string
+	Flags uint16
+	Type uint16
+	ChildLevel int // How deeply the layer is nested inside groups
+	BlendMode uint16
+	Opacity uint8
+	Parent int // The index of the group that contains this layer, or -1
+}
+
+type Cel struct {
+	Layer int
+	X, Y int
+	Opacity uint8
+	Image *image.NRGBA // Shared with the linked frame's cel when the cel is linked
+}
+
+type Frame struct {
+	Duration time.Duration
+	Cels []Cel
+}
+
+type Direction uint8
+const (
+	Forward Direction = iota
+	Reverse
+	PingPong
+	PingPongReverse
+)
+
+// A named range of frames, which is how animations are marked out in aseprite
+type Tag struct {
+	Name string `json:"name"`
+	From int `json:"from"`
+	To int `json:"to"` // Inclusive
+	Direction Direction `json:"direction"`
+	Repeat int `json:"repeat"` // The number of times to play the tag, 0 repeats forever
+}
+
+const (
+	sliceNinePatch = 1 << 0
+	sliceHasPivot = 1 << 1
+	sliceKeySize = 20 // The frame and bounds that every slice key has, in bytes
+)
+
+// Palettes are limited to what an index in a 16 bit image could address, which is already far more than aseprite allows
+const maxPaletteSize = 1 << 16
+
+// A named region of the canvas. Slices can change from frame to frame, so each one has a key for every frame where it changes
+type Slice struct {
+	Name string `json:"name"`
+	Keys []SliceKey `json:"keys"`
+}
+
+type SliceKey struct {
+	Frame int `json:"frame"` // The key applies from this frame until the next key
+	Bounds image.Rectangle `json:"bounds"`
+	Center image.Rectangle `json:"center"` // The middle of a 9-slice, relative to Bounds. Empty if the slice isn't a 9-slice
+	Pivot image.Point `json:"pivot"` // Relative to Bounds
+	HasPivot bool `json:"hasPivot"`
+}
+
+// Returns the key that applies to frame, or false if the slice doesn't exist yet on that frame
+func (s Slice) Key(frame int) (SliceKey, bool) {
+	found := false
+	var key SliceKey
+	for _, k := range s.Keys {
+		if k.Frame > frame { break }
+		key = k
+		found = true
+	}
+	return key, found
+}
+
+type File struct {
+	Width, Height int
+	ColorDepth int // Bits per pixel. 32 is RGBA, 16 is grayscale and 8 is indexed
+	TransparentIndex uint8 // The palette index that is transparent in indexed files
+	Palette color.Palette
+
+	Layers []Layer
+	Frames []Frame
+	Tags []Tag
+	Slices []Slice
+}
+
+// Reads and parses the file at path
+func ReadFile(path string) (*File, error) {
+	dat, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := Decode(bytes.NewReader(dat))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return file, nil
+}
+
+func Decode(r io.Reader) (*File, error) {
+	dat, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(dat) < headerSize {
+		return nil, errors.New("file too short for an aseprite header")
+	}
+
+	h := reader{dat: dat[:headerSize]}
+	h.skip(4) // File size
+	if h.word() != headerMagic {
+		return nil, errors.New("not an aseprite file")
+	}
+	numFrames := int(h.word())
+	file := &File{
+		Width: int(h.word()),
+		Height: int(h.word()),
+		ColorDepth: int(h.word()),
+	}
+	h.skip(4 + 2 + 4 + 4) // Flags, speed and two reserved dwords
+	file.TransparentIndex = h.byte()
+
+	switch file.ColorDepth {
+	case 32, 16, 8:
+	default:
+		return nil, fmt.Errorf("unsupported color depth %d", file.ColorDepth)
+	}
+
+	file.Frames = make([]Frame, numFrames)
+	offset := headerSize
+	for i := range file.Frames {
+		if offset + frameHeaderSize > len(dat) {
+			return nil, fmt.Errorf("frame %d: unexpected end of file", i)
+		}
+		f := reader{dat: dat[offset:]}
+		size := int(f.dword())
+		if size < frameHeaderSize || offset + size > len(dat) {
+			return nil, fmt.Errorf("frame %d: invalid size %d", i, size)
+		}
+		if f.word() != frameMagic {
+			return nil, fmt.Errorf("frame %d: bad magic number", i)
+		}
+		numChunks := int(f.word())
+		file.Frames[i].Duration = time.Duration(f.word()) * time.Millisecond
+		f.skip(2)
+		if newChunks := int(f.dword()); newChunks != 0 {
+			numChunks = newChunks
+		}
+
+		err := file.readChunks(i, &reader{dat: dat[offset + frameHeaderSize:offset + size]}, numChunks)
+		if err != nil {
+			return nil, fmt.Errorf("frame %d: %w", i, err)
+		}
+		offset += size
+	}
+
+	return file, nil
+}
+
+func (file *File) readChunks(frame int, r *reader, numChunks int) error {
+	for i := 0; i < numChunks; i++ {
+		if r.remaining() < 6 {
+			return errors.New("unexpected end of frame")
+		}
+		size := int(r.dword())
+		chunkType := r.word()
+		if size < 6 || size - 6 > r.remaining() {
+			return fmt.Errorf("chunk %#x: invalid size %d", chunkType, size)
+		}
+		chunk := &reader{dat: r.bytes(size - 6)}
+
+		var err error
+		switch chunkType {
+		case chunkLayer:
+			err = file.readLayer(chunk)
+		case chunkCel:
+			err = file.readCel(frame, chunk)
+		case chunkPalette:
+			err = file.readPalette(chunk)
+		case chunkTags:
+			file.readTags(chunk)
+		case chunkSlice:
+			err = file.readSlice(chunk)
+		case chunkOldPalette, chunkOldPalette2:
+			// Only used as a fallback by older versions, the new palette chunk always follows it
+		default:
+			// Chunks that don't affect the image are skipped
+		}
+		if err != nil {
+			return fmt.Errorf("chunk %#x: %w", chunkType, err)
+		}
+		if chunk.err != nil {
+			return fmt.Errorf("chunk %#x: %w", chunkType, chunk.err)
+		}
+	}
+	return nil
+}
+
+func (file *File) readLayer(r *reader) error {
+	layer := Layer{
+		Flags: r.word(),
+		Type: r.word(),
+		ChildLevel: int(r.word()),
+		Parent: -1,
+	}
+	r.skip(4) // Default width and height, which are ignored
+	layer.BlendMode = r.word()
+	layer.Opacity = r.byte()
+	r.skip(3)
+	layer.Name = r.string()
+
+	if layer.Type == LayerTypeTilemap {
+		return fmt.Errorf("layer %q: tilemap layers are not supported", layer.Name)
+	}
+
+	// The parent is the closest group above this layer that is one level shallower
+	for i := len(file.Layers) - 1; i >= 0; i-- {
+		if file.Layers[i].ChildLevel < layer.ChildLevel {
+			layer.Parent = i
+			break
+		}
+	}
+
+	file.Layers = append(file.Layers, layer)
+	return nil
+}
+
+func (file *File) readCel(frame int, r *reader) error {
+	cel := Cel{
+		Layer: int(r.word()),
+		X: int(int16(r.word())),
+		Y: int(int16(r.word())),
+		Opacity: r.byte(),
+	}
+	celType := r.word()
+	r.skip(2 + 5) // Z index and reserved bytes
+
+	if cel.Layer >= len(file.Layers) {
+		return fmt.Errorf("cel references missing layer %d", cel.Layer)
+	}
+
+	switch celType {
+	case celRaw, celCompressed:
+		w := int(r.word())
+		h := int(r.word())
+		pixels := r.rest()
+		if celType == celCompressed {
+			zr, err := zlib.NewReader(bytes.NewReader(pixels))
+			if err != nil {
+				return err
+			}
+			pixels, err = io.ReadAll(zr)
+			if err != nil {
+				return err
+			}
+		}
+		img, err := file.decodePixels(pixels, w, h, file.Layers[cel.Layer].Flags & LayerBackground != 0)
+		if err != nil {
+			return err
+		}
+		cel.Image = img
+	case celLinked:
+		linked := int(r.word())
+		if linked >= frame {
+			return fmt.Errorf("cel links to frame %d which hasn't been read yet", linked)
+		}
+		found := false
+		for _, c := range file.Frames[linked].Cels {
+			if c.Layer == cel.Layer {
+				cel.Image = c.Image
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("cel links to frame %d which has no cel on layer %d", linked, cel.Layer)
+		}
+	default:
+		return fmt.Errorf("unsupported cel type %d", celType)
+	}
+
+	file.Frames[frame].Cels = append(file.Frames[frame].Cels, cel)
+	return nil
+}
+
+func (file *File) readPalette(r *reader) error {
+	size := r.dword()
+	first := int(r.dword())
+	last := int(r.dword())
+	r.skip(8)
+
+	if size > maxPaletteSize {
+		return fmt.Errorf("palette has %d colors, the most supported is %d", size, maxPaletteSize)
+	}
+
+	for len(file.Palette) < int(size) {
+		file.Palette = append(file.Palette, color.NRGBA{})
+	}
+	for i := first; i <= last && i < int(size) && r.err == nil; i++ {
+		flags := r.word()
+		file.Palette[i] = color.NRGBA{r.byte(), r.byte(), r.byte(), r.byte()}
+		if flags & 1 != 0 {
+			r.string() // Color name
+		}
+	}
+	return nil
+}
+
+func (file *File) readTags(r *reader) {
+	count := int(r.word())
+	r.skip(8)
+	for i := 0; i < count && r.err == nil; i++ {
+		tag := Tag{
+			From: int(r.word()),
+			To: int(r.word()),
+			Direction: Direction(r.byte()),
+			Repeat: int(r.word()),
+		}
+		r.skip(6 + 3 + 1) // Reserved bytes and the deprecated tag color
+		tag.Name = r.string()
+		file.Tags = append(file.Tags, tag)
+	}
+}
+
+func (file *File) readSlice(r *reader) error {
+	count := r.dword()
+	flags := r.dword()
+	r.skip(4)
+
+	// Every key takes up at least this many bytes, so a count any higher than the chunk can hold is corrupt
+	if count > uint32(r.remaining() / sliceKeySize) {
+		return fmt.Errorf("slice has %d keys, but the chunk only has room for %d", count, r.remaining() / sliceKeySize)
+	}
+	slice := Slice{
+		Name: r.string(),
+		Keys: make([]SliceKey, count),
+	}
+	for i := range slice.Keys {
+		key := SliceKey{
+			Frame: int(r.dword()),
+		}
+		x, y := int(int32(r.dword())), int(int32(r.dword()))
+		w, h := int(r.dword()), int(r.dword())
+		key.Bounds = image.Rect(x, y, x + w, y + h)
+
+		if flags & sliceNinePatch != 0 {
+			cx, cy := int(int32(r.dword())), int(int32(r.dword()))
+			cw, ch := int(r.dword()), int(r.dword())
+			key.Center = image.Rect(cx, cy, cx + cw, cy + ch)
+		}
+		if flags & sliceHasPivot != 0 {
+			key.Pivot = image.Point{int(int32(r.dword())), int(int32(r.dword()))}
+			key.HasPivot = true
+		}
+		slice.Keys[i] = key
+	}
+	file.Slices = append(file.Slices, slice)
+	return nil
+}
+
+func (file *File) decodePixels(pixels []byte, w, h int, background bool) (*image.NRGBA, error) {
+	bytesPerPixel := file.ColorDepth / 8
+	if len(pixels) < w * h * bytesPerPixel {
+		return nil, fmt.Errorf("cel has %d bytes of pixels, expected %d", len(pixels), w * h * bytesPerPixel)
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for i := 0; i < w * h; i++ {
+		var c color.NRGBA
+		switch file.ColorDepth {
+		case 32:
+			p := pixels[i*4:]
+			c = color.NRGBA{p[0], p[1], p[2], p[3]}
+		case 16:
+			p := pixels[i*2:]
+			c = color.NRGBA{p[0], p[0], p[0], p[1]}
+		case 8:
+			index := pixels[i]
+			// The transparent index only applies to transparent layers
+			if (index != file.TransparentIndex || background) && int(index) < len(file.Palette) {
+				c = color.NRGBAModel.Convert(file.Palette[index]).(color.NRGBA)
+			}
+		}
+		img.SetNRGBA(i % w, i / w, c)
+	}
+	return img, nil
+}
+
+// Returns true if the layer and every group it's nested in are visible. Reference layers are never considered visible
+func (file *File) LayerVisible(index int) bool {
+	for i := index; i >= 0; i = file.Layers[i].Parent {
+		flags := file.Layers[i].Flags
+		if flags & LayerVisible == 0 || flags & LayerReference != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the index of the first layer with the given name
+func (file *File) Layer(name string) (int, bool) {
+	for i, layer := range file.Layers {
+		if layer.Name == name {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+func (file *File) Tag(name string) (Tag, bool) {
+	for _, tag := range file.Tags {
+		if tag.Name == name {
+			return tag, true
+		}
+	}
+	return Tag{}, false
+}
+
+func (file *File) Slice(name string) (Slice, bool) {
+	for _, slice := range file.Slices {
+		if slice.Name == name {
+			return slice, true
+		}
+	}
+	return Slice{}, false
+}
+
+// Flattens the visible layers of a frame into a single image the size of the canvas. Every blend mode is drawn as normal
+func (file *File) FrameImage(frame int) *image.NRGBA {
+	return file.LayersImage(frame, file.LayerVisible)
+}
+
+// Flattens the layers of a frame that include returns true for. This can be used to export layers separately
+func (file *File) LayersImage(frame int, include func(layer int) bool) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, file.Width, file.Height))
+	for _, cel := range file.Frames[frame].Cels {
+		if cel.Image == nil || !include(cel.Layer) { continue }
+
+		opacity := uint16(cel.Opacity) * uint16(file.Layers[cel.Layer].Opacity) / 255
+		mask := image.NewUniform(color.Alpha{uint8(opacity)})
+		bounds := cel.Image.Bounds().Add(image.Point{cel.X, cel.Y})
+		draw.DrawMask(img, bounds, cel.Image, image.Point{}, mask, image.Point{}, draw.Over)
+	}
+	return img
+}
+
+// A little endian reader that records the first out of bounds read instead of failing every call
+type reader struct {
+	dat []byte
+	pos int
+	err error
+}
+
+func (r *reader) remaining() int {
+	return len(r.dat) - r.pos
+}
+
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil || n > r.remaining() {
+		r.err = io.ErrUnexpectedEOF
+		return make([]byte, n)
+	}
+	b := r.dat[r.pos:r.pos + n]
+	r.pos += n
+	return b
+}
+
+func (r *reader) rest() []byte {
+	return r.bytes(r.remaining())
+}
+
+func (r *reader) skip(n int) {
+	r.bytes(n)
+}
+
+func (r *reader) byte() uint8 {
+	return r.bytes(1)[0]
+}
+
+func (r *reader) word() uint16 {
+	return binary.LittleEndian.Uint16(r.bytes(2))
+}
+
+func (r *reader) dword() uint32 {
+	return binary.LittleEndian.Uint32(r.bytes(4))
+}
+
+func (r *reader) string() string {
+	return string(r.bytes(int(r.word())))
+}
@@ -1,7 +1,7 @@
 package main
 
 import (
-	// "fmt"
+	"fmt"
 	// "time"
 	// "io"
 	"github.com/hajimehoshi/go-mp3"
@@ -10,28 +10,31 @@ import (
 	"github.com/unitoftime/flow/asset"
 )
 
-func LoadMp3(load *asset.Load, name string) *mp3.Decoder {
+func LoadMp3(load *asset.Load, name string) (*mp3.Decoder, error) {
 	// Open the file for reading. Do NOT close before you finish playing!
 	file, err := load.Open(name)
 	if err != nil {
-		panic("opening failed: " + err.Error())
+		return nil, err
 	}
 
 	// Decode file. This process is done as the file plays so it won't
 	// load the whole thing into memory.
 	decodedMp3, err := mp3.NewDecoder(file)
 	if err != nil {
-		panic("mp3.NewDecoder failed: " + err.Error())
+		file.Close()
+		return nil, fmt.Errorf("decoding %s: %w", name, err)
 	}
-	return decodedMp3
+	return decodedMp3, nil
 }
 
 type AudioPlayer struct {
 	ctx *oto.Context
 	player oto.Player
+	volume float64
+	muted bool
 }
 
-func NewAudioPlayer() *AudioPlayer {
+func NewAudioPlayer() (*AudioPlayer, error) {
 	// Usually 44100 or 48000. Other values might cause distortions in Oto
 	samplingRate := 44100
 
@@ -45,18 +48,29 @@ func NewAudioPlayer() *AudioPlayer {
 	// Remember that you should **not** create more than one context
 	otoCtx, readyChan, err := oto.NewContext(samplingRate, numOfChannels, audioBitDepth)
 	if err != nil {
-		panic("oto.NewContext failed: " + err.Error())
+		return nil, fmt.Errorf("creating audio context: %w", err)
 	}
 	// It might take a bit for the hardware audio devices to be ready, so we wait on the channel.
 	<-readyChan
 
 	return &AudioPlayer{
 		ctx: otoCtx,
+		volume: 0.5,
+	}, nil
+}
+
+func (a *AudioPlayer) SetVolume(volume float64) {
+	a.volume = volume
+	if a.player != nil {
+		a.player.SetVolume(volume)
 	}
 }
 
-func (a *AudioPlayer) TogglePlayPause() {
-	if a.player.IsPlaying() {
+func (a *AudioPlayer) SetMuted(muted bool) {
+	a.muted = muted
+	if a.player == nil { return }
+
+	if muted {
 		a.player.Pause()
 	} else {
 		a.player.Play()
@@ -67,12 +81,19 @@ func (a *AudioPlayer) Play(decoder *mp3.Decoder) {
 	infLoop := NewInfiniteLoop(decoder, decoder.Length() - 10000) // TODO: I'm not sure why, but there seem to be some blank samples at the end or something, so I just trim those off (about 10k). Makes the loop better
 
 	go func() {
+		// Only one track plays at a time
+		if a.player != nil {
+			a.player.Close()
+		}
+
 		player := a.ctx.NewPlayer(infLoop)
-		player.SetVolume(0.5)
+		player.SetVolume(a.volume)
 		a.player = player
 
 		// Play starts playing the sound and returns without waiting for it (Play() is async).
-		player.Play()
+		if !a.muted {
+			player.Play()
+		}
 
 		// for player.IsPlaying() {
 		// 	time.Sleep(1 * time.Millisecond)
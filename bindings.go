@@ -0,0 +1,322 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/unitoftime/glitch"
+)
+
+// The names used for actions in the settings file and on the controls screen
+var actionNames = [ActionLast + 1]string{
+	ActionLeft: "Move Left",
+	ActionRight: "Move Right",
+	ActionDrop: "Drop",
+	ActionRotateLeft: "Rotate Left",
+	ActionRotateRight: "Rotate Right",
+	ActionHold: "Hold",
+	ActionPreview: "Toggle Preview",
+	ActionPause: "Pause",
+	ActionMute: "Mute",
+	ActionConfirm: "Menu Confirm",
+	ActionControls: "Menu Controls",
+}
+
+// Actions are only checked in certain modes, so two actions can share a key as long as they are never active at the same time
+type actionContext uint8
+const (
+	contextGlobal actionContext = iota
+	contextGame
+	contextMenu
+)
+
+var actionContexts = [ActionLast + 1]actionContext{
+	ActionLeft: contextGame,
+	ActionRight: contextGame,
+	ActionDrop: contextGame,
+	ActionRotateLeft: contextGame,
+	ActionRotateRight: contextGame,
+	ActionHold: contextGame,
+	ActionPreview: contextGame,
+	ActionPause: contextGame,
+	ActionMute: contextGlobal,
+	ActionConfirm: contextMenu,
+	ActionControls: contextMenu,
+}
+
+func contextsOverlap(a, b actionContext) bool {
+	return a == b || a == contextGlobal || b == contextGlobal
+}
+
+// The keys and mouse buttons that can be bound
+var keyNames = map[glitch.Key]string{
+	glitch.KeySpace: "Space",
+	glitch.KeyApostrophe: "Apostrophe",
+	glitch.KeyComma: "Comma",
+	glitch.KeyMinus: "Minus",
+	glitch.KeyPeriod: "Period",
+	glitch.KeySlash: "Slash",
+	glitch.Key0: "0",
+	glitch.Key1: "1",
+	glitch.Key2: "2",
+	glitch.Key3: "3",
+	glitch.Key4: "4",
+	glitch.Key5: "5",
+	glitch.Key6: "6",
+	glitch.Key7: "7",
+	glitch.Key8: "8",
+	glitch.Key9: "9",
+	glitch.KeySemicolon: "Semicolon",
+	glitch.KeyEqual: "Equal",
+	glitch.KeyA: "A",
+	glitch.KeyB: "B",
+	glitch.KeyC: "C",
+	glitch.KeyD: "D",
+	glitch.KeyE: "E",
+	glitch.KeyF: "F",
+	glitch.KeyG: "G",
+	glitch.KeyH: "H",
+	glitch.KeyI: "I",
+	glitch.KeyJ: "J",
+	glitch.KeyK: "K",
+	glitch.KeyL: "L",
+	glitch.KeyM: "M",
+	glitch.KeyN: "N",
+	glitch.KeyO: "O",
+	glitch.KeyP: "P",
+	glitch.KeyQ: "Q",
+	glitch.KeyR: "R",
+	glitch.KeyS: "S",
+	glitch.KeyT: "T",
+	glitch.KeyU: "U",
+	glitch.KeyV: "V",
+	glitch.KeyW: "W",
+	glitch.KeyX: "X",
+	glitch.KeyY: "Y",
+	glitch.KeyZ: "Z",
+	glitch.KeyLeftBracket: "Left Bracket",
+	glitch.KeyBackslash: "Backslash",
+	glitch.KeyRightBracket: "Right Bracket",
+	glitch.KeyGraveAccent: "Grave Accent",
+	glitch.KeyEscape: "Escape",
+	glitch.KeyEnter: "Enter",
+	glitch.KeyTab: "Tab",
+	glitch.KeyBackspace: "Backspace",
+	glitch.KeyInsert: "Insert",
+	glitch.KeyDelete: "Delete",
+	glitch.KeyRight: "Right",
+	glitch.KeyLeft: "Left",
+	glitch.KeyDown: "Down",
+	glitch.KeyUp: "Up",
+	glitch.KeyPageUp: "Page Up",
+	glitch.KeyPageDown: "Page Down",
+	glitch.KeyHome: "Home",
+	glitch.KeyEnd: "End",
+	glitch.KeyF1: "F1",
+	glitch.KeyF2: "F2",
+	glitch.KeyF3: "F3",
+	glitch.KeyF4: "F4",
+	glitch.KeyF5: "F5",
+	glitch.KeyF6: "F6",
+	glitch.KeyF7: "F7",
+	glitch.KeyF8: "F8",
+	glitch.KeyF9: "F9",
+	glitch.KeyF10: "F10",
+	glitch.KeyF11: "F11",
+	glitch.KeyF12: "F12",
+	glitch.KeyKP0: "Numpad 0",
+	glitch.KeyKP1: "Numpad 1",
+	glitch.KeyKP2: "Numpad 2",
+	glitch.KeyKP3: "Numpad 3",
+	glitch.KeyKP4: "Numpad 4",
+	glitch.KeyKP5: "Numpad 5",
+	glitch.KeyKP6: "Numpad 6",
+	glitch.KeyKP7: "Numpad 7",
+	glitch.KeyKP8: "Numpad 8",
+	glitch.KeyKP9: "Numpad 9",
+	glitch.KeyKPDecimal: "Numpad Decimal",
+	glitch.KeyKPDivide: "Numpad Divide",
+	glitch.KeyKPMultiply: "Numpad Multiply",
+	glitch.KeyKPSubtract: "Numpad Subtract",
+	glitch.KeyKPAdd: "Numpad Add",
+	glitch.KeyKPEnter: "Numpad Enter",
+	glitch.KeyLeftShift: "Left Shift",
+	glitch.KeyLeftControl: "Left Control",
+	glitch.KeyLeftAlt: "Left Alt",
+	glitch.KeyRightShift: "Right Shift",
+	glitch.KeyRightControl: "Right Control",
+	glitch.KeyRightAlt: "Right Alt",
+	glitch.MouseButtonLeft: "Mouse Left",
+	glitch.MouseButtonRight: "Mouse Right",
+	glitch.MouseButtonMiddle: "Mouse Middle",
+}
+
+var buttonNames = [GamepadButtonLast + 1]string{
+	GamepadA: "Pad A",
+	GamepadB: "Pad B",
+	GamepadX: "Pad X",
+	GamepadY: "Pad Y",
+	GamepadLeftBumper: "Pad LB",
+	GamepadRightBumper: "Pad RB",
+	GamepadBack: "Pad Back",
+	GamepadStart: "Pad Start",
+	GamepadDpadUp: "Pad Up",
+	GamepadDpadRight: "Pad Right",
+	GamepadDpadDown: "Pad Down",
+	GamepadDpadLeft: "Pad Left",
+}
+
+func lookupAction(name string) (Action, bool) {
+	for action, n := range actionNames {
+		if n == name {
+			return Action(action), true
+		}
+	}
+	return 0, false
+}
+
+func lookupKey(name string) (glitch.Key, bool) {
+	for key, n := range keyNames {
+		if n == name {
+			return key, true
+		}
+	}
+	return glitch.KeyUnknown, false
+}
+
+func lookupButton(name string) (GamepadButton, bool) {
+	for button, n := range buttonNames {
+		if n == name {
+			return GamepadButton(button), true
+		}
+	}
+	return 0, false
+}
+
+// Returns the action that already uses the key in an overlapping context, if any
+func (b Bindings) KeyConflict(action Action, key glitch.Key) (Action, bool) {
+	for other, keys := range b.Keys {
+		if other == action { continue }
+		if !contextsOverlap(actionContexts[action], actionContexts[other]) { continue }
+		for _, k := range keys {
+			if k == key {
+				return other, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// Returns the action that already uses the gamepad button in an overlapping context, if any
+func (b Bindings) ButtonConflict(action Action, button GamepadButton) (Action, bool) {
+	for other, buttons := range b.Buttons {
+		if other == action { continue }
+		if !contextsOverlap(actionContexts[action], actionContexts[other]) { continue }
+		for _, btn := range buttons {
+			if btn == button {
+				return other, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// Lists every key or button that is bound to more than one action in an overlapping context
+func (b Bindings) Conflicts() []string {
+	conflicts := make([]string, 0)
+	for action := Action(0); action <= ActionLast; action++ {
+		for _, key := range b.Keys[action] {
+			if other, conflict := b.KeyConflict(action, key); conflict && other > action {
+				conflicts = append(conflicts, fmt.Sprintf("%s is used by %s and %s", keyNames[key], actionNames[action], actionNames[other]))
+			}
+		}
+		for _, button := range b.Buttons[action] {
+			if other, conflict := b.ButtonConflict(action, button); conflict && other > action {
+				conflicts = append(conflicts, fmt.Sprintf("%s is used by %s and %s", buttonNames[button], actionNames[action], actionNames[other]))
+			}
+		}
+	}
+	return conflicts
+}
+
+// Describes the bindings of an action for the controls screen
+func (b Bindings) Describe(action Action) string {
+	str := ""
+	for _, key := range b.Keys[action] {
+		if str != "" { str += ", " }
+		str += keyNames[key]
+	}
+	for _, button := range b.Buttons[action] {
+		if str != "" { str += ", " }
+		str += buttonNames[button]
+	}
+	if str == "" {
+		return "Unbound"
+	}
+	return str
+}
+
+// Returns the name of the first key bound to an action, for on screen prompts
+func (b Bindings) KeyName(action Action) string {
+	keys := b.Keys[action]
+	if len(keys) == 0 {
+		return "Unbound"
+	}
+	return keyNames[keys[0]]
+}
+
+// The settings file representation of an action's bindings
+type BindingNames struct {
+	Keys []string `json:"keys"`
+	Buttons []string `json:"buttons"`
+}
+
+func (b Bindings) Names() map[string]BindingNames {
+	names := make(map[string]BindingNames)
+	for action := Action(0); action <= ActionLast; action++ {
+		n := BindingNames{
+			Keys: make([]string, 0),
+			Buttons: make([]string, 0),
+		}
+		for _, key := range b.Keys[action] {
+			n.Keys = append(n.Keys, keyNames[key])
+		}
+		for _, button := range b.Buttons[action] {
+			n.Buttons = append(n.Buttons, buttonNames[button])
+		}
+		names[actionNames[action]] = n
+	}
+	return names
+}
+
+// Builds bindings from their settings file representation. Actions missing from the file keep their default bindings
+func BindingsFromNames(names map[string]BindingNames) (Bindings, error) {
+	bindings := DefaultBindings()
+	for actionName, n := range names {
+		action, ok := lookupAction(actionName)
+		if !ok {
+			return DefaultBindings(), fmt.Errorf("unknown action: %s", actionName)
+		}
+
+		keys := make([]glitch.Key, 0, len(n.Keys))
+		for _, name := range n.Keys {
+			key, ok := lookupKey(name)
+			if !ok {
+				return DefaultBindings(), fmt.Errorf("unknown key for %s: %s", actionName, name)
+			}
+			keys = append(keys, key)
+		}
+
+		buttons := make([]GamepadButton, 0, len(n.Buttons))
+		for _, name := range n.Buttons {
+			button, ok := lookupButton(name)
+			if !ok {
+				return DefaultBindings(), fmt.Errorf("unknown gamepad button for %s: %s", actionName, name)
+			}
+			buttons = append(buttons, button)
+		}
+
+		bindings.Keys[action] = keys
+		bindings.Buttons[action] = buttons
+	}
+	return bindings, nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"github.com/jakecoffman/cp"
+
+	"github.com/unitoftime/glitch"
+)
+
+// The category that each package sprite gets sorted by
+var packageCategories = map[string]string{
+	"package-0.png": "box",
+	"package-1.png": "box",
+	"package-2.png": "box",
+	"package-3.png": "box",
+	"package-4.png": "fragile",
+	"package-5.png": "box",
+	"package-6.png": "box",
+	"package-7.png": "jomy",
+	"package-8.png": "letter",
+	"package-9.png": "letter",
+	"package-10.png": "letter",
+	"package-11.png": "letter",
+}
+
+// The odds of each package sprite showing up on the line
+var packageTable = NewRngTable(
+	NewRngItem(20, "package-0.png"),
+	NewRngItem(20, "package-1.png"),
+	NewRngItem(20, "package-2.png"),
+	NewRngItem(20, "package-3.png"),
+	NewRngItem(20, "package-4.png"),
+	NewRngItem(20, "package-5.png"),
+	NewRngItem(20, "package-6.png"),
+	NewRngItem(10, "package-7.png"),
+	NewRngItem(1, "package-8.png"),
+	NewRngItem(1, "package-9.png"),
+	NewRngItem(1, "package-10.png"),
+	NewRngItem(1, "package-11.png"),
+)
+
+// The package drawn on a bin's label for each category
+var categoryIcons = map[string]string{
+	"box": "package-0.png",
+	"fragile": "package-4.png",
+	"jomy": "package-7.png",
+	"letter": "package-8.png",
+}
+
+// A destination at the bottom of the level. An empty category list accepts every package
+type Bin struct {
+	Categories []string `json:"categories"`
+	rect glitch.Rect
+}
+
+func (b Bin) Accepts(category string) bool {
+	if len(b.Categories) == 0 {
+		return true
+	}
+	for _, c := range b.Categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// Bin setups used when the level data doesn't list any bins. Every category must be accepted by at least one bin
+var binBands = []difficultyBand[[]Bin]{
+	{8, NewRngTable(
+		NewRngItem(1, []Bin{
+			{Categories: []string{"box"}},
+			{Categories: []string{"fragile", "jomy"}},
+			{Categories: []string{"letter"}},
+		}),
+	)},
+	{4, NewRngTable(
+		NewRngItem(1, []Bin{
+			{Categories: []string{"box", "fragile"}},
+			{Categories: []string{"letter", "jomy"}},
+		}),
+		NewRngItem(1, []Bin{
+			{Categories: []string{"letter", "jomy"}},
+			{Categories: []string{"box", "fragile"}},
+		}),
+	)},
+}
+
+const binDividerHeight = 150.0
+
+// Splits the accept area evenly between the bins and builds the dividers that separate them
+func (g *Game) AddBins(bins []Bin) error {
+	if len(bins) == 0 {
+		bins = rollBand(binBands, g.difficulty, []Bin{{}})
+	}
+
+	g.bins = make([]Bin, len(bins))
+	width := g.acceptBounds.W() / float64(len(bins))
+	for i := range bins {
+		g.bins[i] = Bin{
+			Categories: bins[i].Categories,
+			rect: glitch.R(
+				g.acceptBounds.Min[0] + float64(i) * width, g.acceptBounds.Min[1],
+				g.acceptBounds.Min[0] + float64(i + 1) * width, g.acceptBounds.Max[1],
+			),
+		}
+	}
+
+	thickness := 12.0
+	ninePanel, err := g.spritesheet.GetNinePanel(wallSprite, glitch.R(4, 4, 4, 4))
+	if err != nil {
+		return err
+	}
+	for i := 1; i < len(g.bins); i++ {
+		x := g.bins[i].rect.Min[0]
+		divider := glitch.R(x - thickness/2, g.acceptBounds.Min[1], x + thickness/2, g.acceptBounds.Min[1] + binDividerHeight)
+
+		s := NewSprite(nil)
+		s.ninePanel = ninePanel
+		s.rect = glitch.R(-divider.W()/2, -divider.H()/2, divider.W()/2, divider.H()/2)
+
+		shape := makeWall(s, divider)
+		g.space.AddBody(shape.Body())
+		g.space.AddShape(shape)
+	}
+	return nil
+}
+
+// Counts how many packages landed in a bin that accepts them, landed in the wrong bin, or missed the bins entirely
+func (g *Game) SortPackages() (accepted, wrongBin, lost int) {
+	g.space.EachShape(func(shape *cp.Shape) {
+		sprite := shape.Body().UserData.(Sprite)
+		if !sprite.isPackage { return } // Skip if not a package
+
+		bin, ok := g.PackageBin(shape)
+		if !ok {
+			lost++
+		} else if bin.Accepts(sprite.category) {
+			accepted++
+		} else {
+			wrongBin++
+		}
+	})
+	return
+}
+
+// Returns the bin that the package shape is entirely inside of, if there is one
+func (g *Game) PackageBin(shape *cp.Shape) (Bin, bool) {
+	bb := shape.BB()
+	for _, bin := range g.bins {
+		areaBB := cp.BB{
+			L: bin.rect.Min[0],
+			B: bin.rect.Min[1],
+			R: bin.rect.Max[0],
+			T: bin.rect.Max[1],
+		}
+		if areaBB.Contains(bb) {
+			return bin, true
+		}
+	}
+	return Bin{}, false
+}
+
+// Draws the accepted package icons above each bin so the player knows where to route things
+func (g *Game) DrawBins(pass *glitch.RenderPass) error {
+	// A single catch-all bin doesn't need a label
+	if len(g.bins) <= 1 { return nil }
+
+	iconSize := 40.0
+	for _, bin := range g.bins {
+		center := bin.rect.Center()
+		y := bin.rect.Min[1] + binDividerHeight + iconSize
+
+		startX := center[0] - float64(len(bin.Categories) - 1) * iconSize / 2
+		for i, category := range bin.Categories {
+			sprite, err := g.spritesheet.Get(categoryIcons[category])
+			if err != nil {
+				return err
+			}
+
+			bounds := sprite.Bounds()
+			scale := iconSize / bounds.W()
+			if bounds.H() > bounds.W() {
+				scale = iconSize / bounds.H()
+			}
+
+			mat := glitch.Mat4Ident
+			mat.Scale(scale, scale, 1)
+			mat.Translate(startX + float64(i) * iconSize, y, 0)
+			sprite.Draw(pass, mat)
+		}
+	}
+	return nil
+}
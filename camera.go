@@ -0,0 +1,214 @@
+package main
+
+import (
+	"math"
+
+	"github.com/unitoftime/glitch"
+)
+
+// Everything is laid out for this resolution. It gets scaled to fit the window, with bars covering whatever is left over
+const (
+	virtualWidth = 1920.0
+	virtualHeight = 1080.0
+)
+
+var letterboxColor = glitch.Black
+
+// Scales the virtual resolution to fit the window while keeping its aspect ratio
+type Viewport struct {
+	camera *glitch.CameraOrtho
+	worldCamera *glitch.CameraOrtho // The camera with effects applied, which only the level is drawn with
+	bounds glitch.Rect // The window in framebuffer pixels
+	scale float64
+	screen glitch.Rect // The virtual screen in world coordinates, centered on the origin
+	window glitch.Rect // The window in world coordinates, which is larger than the screen along one axis when letterboxed
+
+	pass *glitch.RenderPass // Draws the bars over everything else
+	bar *glitch.Mesh // A unit square that gets stretched over each bar
+}
+
+func NewViewport(shader *glitch.Shader) *Viewport {
+	geom := glitch.NewGeomDraw()
+	geom.SetColor(letterboxColor)
+	return &Viewport{
+		camera: glitch.NewCameraOrtho(),
+		worldCamera: glitch.NewCameraOrtho(),
+		scale: 1,
+		screen: glitch.R(0, 0, virtualWidth, virtualHeight).CenterAt(glitch.Vec2{}),
+		pass: glitch.NewRenderPass(shader),
+		bar: geom.FillRect(glitch.R(0, 0, 1, 1)),
+	}
+}
+
+// Must be called once per frame before anything is projected, so that window resizes are picked up
+func (v *Viewport) Update(win *glitch.Window) {
+	bounds := win.Bounds()
+	v.bounds = bounds
+	v.scale = bounds.W() / virtualWidth
+	if scaleY := bounds.H() / virtualHeight; scaleY < v.scale {
+		v.scale = scaleY
+	}
+
+	v.camera.SetOrtho2D(bounds)
+	center := bounds.Center()
+	v.camera.SetView2D(-center[0], -center[1], v.scale, v.scale)
+
+	v.window = glitch.R(0, 0, bounds.W() / v.scale, bounds.H() / v.scale).CenterAt(glitch.Vec2{})
+}
+
+func (v *Viewport) Camera() *glitch.CameraOrtho {
+	return v.camera
+}
+
+func (v *Viewport) Screen() glitch.Rect {
+	return v.screen
+}
+
+// The number of draw calls the letterbox made last frame
+func (v *Viewport) DrawCalls() int {
+	return v.pass.Stats().DrawCalls
+}
+
+// Returns a camera that centers on focus and is zoomed in by zoom on top of the usual scale. The UI keeps using the plain camera so that it stays put
+func (v *Viewport) WorldCamera(focus glitch.Vec2, zoom float64) *glitch.CameraOrtho {
+	scale := v.scale * zoom
+	center := v.bounds.Center()
+	v.worldCamera.SetOrtho2D(v.bounds)
+	v.worldCamera.SetView2D(focus[0] - center[0], focus[1] - center[1], scale, scale)
+	return v.worldCamera
+}
+
+// Converts a point in framebuffer pixels into world coordinates
+func (v *Viewport) Unproject(x, y float64) glitch.Vec3 {
+	return v.camera.Unproject(glitch.Vec3{x, y, 0})
+}
+
+// Covers the parts of the window outside of the virtual screen
+func (v *Viewport) DrawLetterbox(target glitch.Target) {
+	v.pass.Clear()
+
+	bars := []glitch.Rect{
+		glitch.R(v.window.Min[0], v.window.Min[1], v.screen.Min[0], v.window.Max[1]), // Left
+		glitch.R(v.screen.Max[0], v.window.Min[1], v.window.Max[0], v.window.Max[1]), // Right
+		glitch.R(v.screen.Min[0], v.window.Min[1], v.screen.Max[0], v.screen.Min[1]), // Bottom
+		glitch.R(v.screen.Min[0], v.screen.Max[1], v.screen.Max[0], v.window.Max[1]), // Top
+	}
+	for _, bar := range bars {
+		if bar.W() <= 0 || bar.H() <= 0 { continue }
+
+		mat := glitch.Mat4Ident
+		mat.Scale(bar.W(), bar.H(), 1).Translate(bar.Min[0], bar.Min[1], 0)
+		v.bar.Draw(v.pass, mat)
+	}
+
+	v.pass.SetUniform("projection", v.camera.Projection)
+	v.pass.SetUniform("view", v.camera.View)
+	v.pass.Draw(target)
+}
+
+const (
+	maxShakeOffset = 25.0 // How far the camera moves at full trauma, in virtual pixels
+	shakeFrequency = 25.0
+	traumaDecay = 1.5 // Trauma lost per second
+	zoomSpeed = 4.0 // How quickly the zoom eases towards its target. Higher is faster
+	maxZoom = 2.0
+)
+
+// Screen shake, hit-stop and zooming for the level camera. Shake comes from trauma, which impacts add to and which wears off over time. The shake is trauma squared, so small bumps barely register while big ones are felt
+type CameraEffects struct {
+	trauma float64
+	time float64
+	hitStop float64 // Seconds left before the simulation can carry on
+
+	zoom, zoomTarget float64
+	focus, focusTarget glitch.Vec2
+
+	// Accessibility settings
+	shakeScale float64 // Multiplies the shake, 0 turns it off
+	hitStopEnabled bool
+	zoomEnabled bool
+}
+
+func NewCameraEffects() *CameraEffects {
+	return &CameraEffects{
+		zoom: 1,
+		zoomTarget: 1,
+		shakeScale: 1,
+		hitStopEnabled: true,
+		zoomEnabled: true,
+	}
+}
+
+func (e *CameraEffects) Apply(settings Settings) {
+	e.shakeScale = settings.ScreenShake
+	e.hitStopEnabled = settings.HitStop
+	e.zoomEnabled = settings.CameraZoom
+	if e.shakeScale <= 0 {
+		e.trauma = 0
+	}
+	if !e.hitStopEnabled {
+		e.hitStop = 0
+	}
+}
+
+// Adds trauma between 0 and 1. Trauma is capped at 1
+func (e *CameraEffects) AddTrauma(amount float64) {
+	if e.shakeScale <= 0 { return }
+	e.trauma = math.Min(1, e.trauma + amount)
+}
+
+// Freezes the simulation for the duration, in seconds. Overlapping hit-stops don't stack
+func (e *CameraEffects) HitStop(duration float64) {
+	if !e.hitStopEnabled { return }
+	e.hitStop = math.Max(e.hitStop, duration)
+}
+
+// Returns true while a hit-stop is holding the simulation still
+func (e *CameraEffects) Frozen() bool {
+	return e.hitStop > 0
+}
+
+// Eases the camera in until rect fills most of the screen
+func (e *CameraEffects) ZoomTo(rect, screen glitch.Rect) {
+	if !e.zoomEnabled || rect.W() <= 0 || rect.H() <= 0 {
+		e.ResetZoom()
+		return
+	}
+	zoom := 0.9 * math.Min(screen.W() / rect.W(), screen.H() / rect.H())
+	e.zoomTarget = math.Max(1, math.Min(maxZoom, zoom))
+	e.focusTarget = rect.Center()
+}
+
+// Eases the camera back out to show the whole screen
+func (e *CameraEffects) ResetZoom() {
+	e.zoomTarget = 1
+	e.focusTarget = glitch.Vec2{}
+}
+
+func (e *CameraEffects) Update(dt float64) {
+	e.time += dt
+	e.trauma = math.Max(0, e.trauma - traumaDecay * dt)
+	e.hitStop = math.Max(0, e.hitStop - dt)
+
+	// Exponential easing, so that it looks the same at any frame rate
+	t := 1 - math.Exp(-zoomSpeed * dt)
+	e.zoom += (e.zoomTarget - e.zoom) * t
+	e.focus = e.focus.Add(e.focusTarget.Sub(e.focus).Scaled(t))
+}
+
+// Returns how far the shake moves the camera this frame
+func (e *CameraEffects) Offset() glitch.Vec2 {
+	shake := e.trauma * e.trauma * e.shakeScale
+	if shake <= 0 { return glitch.Vec2{} }
+
+	// A few sine waves at unrelated frequencies are smooth, but never line up into an obvious pattern
+	t := e.time * shakeFrequency
+	x := (math.Sin(t) + math.Sin(2.3 * t + 1.7) + math.Sin(4.1 * t + 4.2)) / 3
+	y := (math.Sin(1.3 * t + 0.5) + math.Sin(2.9 * t + 2.9) + math.Sin(3.7 * t + 5.3)) / 3
+	return glitch.Vec2{x, y}.Scaled(maxShakeOffset * shake)
+}
+
+// Returns the level camera for this frame
+func (e *CameraEffects) Camera(viewport *Viewport) *glitch.CameraOrtho {
+	return viewport.WorldCamera(e.focus.Add(e.Offset()), e.zoom)
+}
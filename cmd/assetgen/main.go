@@ -0,0 +1,149 @@
+// Command assetgen exports the frames of the aseprite files in the repo root and packs them into assets/spritesheet.png and assets/spritesheet.json. The frame durations, tags and slices that don't survive packing are written to assets/animations.json
+//
+// Run it from the repo root with go generate or go run ./cmd/assetgen
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/unitoftime/packer"
+
+	"github.com/unitoftime/boxlin/aseprite"
+)
+
+type source struct {
+	name string // The aseprite file name without its extension. Frames are named name-0.png, name-1.png and so on
+	trim bool // Crops transparent borders off of each frame
+}
+
+var sources = []source{
+	{"package", true},
+	{"peg", true},
+	{"peg-bumper", true},
+	{"peg-linear", true},
+	{"peg-orbit", true},
+	{"peg-breakable", true},
+	{"peg-bar", true},
+	{"package-land", true},
+	{"wall", true},
+	{"packing-line", true},
+	{"background", false},
+}
+
+func main() {
+	inputFlag := flag.String("input", ".", "the directory containing the aseprite files")
+	outputFlag := flag.String("output", "assets/spritesheet", "the path of the output png and json, without an extension")
+	animationsFlag := flag.String("animations", "assets/animations.json", "the path of the output animation data")
+	extrudeFlag := flag.Int("extrude", 1, "the number of pixels to extrude each frame by")
+	sizeFlag := flag.Int("size", 1024, "the width and height of the packed spritesheet")
+	statsFlag := flag.Bool("stats", false, "print packing statistics")
+	flag.Parse()
+
+	err := run(*inputFlag, *outputFlag, *animationsFlag, *extrudeFlag, *sizeFlag, *statsFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(input, output, animationsPath string, extrude, size int, stats bool) error {
+	start := time.Now()
+
+	images := make([]packer.ImageData, 0)
+	animations := make(map[string]aseprite.Animation)
+	for _, src := range sources {
+		file, err := aseprite.ReadFile(filepath.Join(input, src.name + ".ase"))
+		if err != nil {
+			return err
+		}
+
+		frameName := func(i int) string {
+			return fmt.Sprintf("%s-%d.png", src.name, i)
+		}
+		offsets := make([]image.Point, len(file.Frames))
+		for i := range file.Frames {
+			img := file.FrameImage(i)
+			if src.trim {
+				img, offsets[i] = trim(img)
+			}
+			images = append(images, packer.NewImageData(img, frameName(i)))
+		}
+		animations[src.name] = file.Animation(frameName, offsets)
+	}
+	numImages := len(images)
+
+	packer.PrepareImageList(images, extrude)
+	images = packer.BasicScanlinePacker(images, size, size)
+	if len(images) != numImages {
+		return fmt.Errorf("only %d of %d frames fit in a %dx%d spritesheet", len(images), numImages, size, size)
+	}
+
+	pngName := output + ".png"
+	atlas, data := packer.Pack(pngName, images, size, size)
+
+	dat, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(output + ".json", dat, 0644)
+	if err != nil {
+		return err
+	}
+
+	dat, err = json.MarshalIndent(animations, "", "\t")
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(animationsPath, dat, 0644)
+	if err != nil {
+		return err
+	}
+
+	pngFile, err := os.Create(pngName)
+	if err != nil {
+		return err
+	}
+	defer pngFile.Close()
+	err = png.Encode(pngFile, atlas)
+	if err != nil {
+		return err
+	}
+
+	if stats {
+		packedArea := 0
+		for i := range images {
+			packedArea += images[i].Area()
+		}
+		fmt.Println("Packed", numImages, "frames in", time.Since(start))
+		fmt.Printf("Efficiency: %.2f%%\n", 100 * float64(packedArea) / float64(size * size))
+	}
+	return pngFile.Close()
+}
+
+// Crops away the fully transparent rows and columns around the image and returns how much was cut from the top left. The packer expects images to start at the origin, so the result is copied into a new image
+func trim(img *image.NRGBA) (*image.NRGBA, image.Point) {
+	bounds := image.Rectangle{}
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			if img.NRGBAAt(x, y).A == 0 { continue }
+			bounds = bounds.Union(image.Rect(x, y, x + 1, y + 1))
+		}
+	}
+
+	// A blank frame still needs a pixel so that it can be packed
+	if bounds.Empty() {
+		bounds = image.Rect(0, 0, 1, 1)
+	}
+
+	trimmed := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(trimmed, trimmed.Bounds(), img, bounds.Min, draw.Src)
+	return trimmed, bounds.Min
+}
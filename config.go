@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"strings"
+)
+
+// Launch options. These are read from the config file and then overridden by any flags that were passed. Unlike Settings they are never written back
+type Config struct {
+	Width int `json:"width"`
+	Height int `json:"height"`
+	Fullscreen bool `json:"fullscreen"`
+	Vsync bool `json:"vsync"`
+	Samples int `json:"samples"` // MSAA samples, 0 disables multisampling
+	Difficulty int `json:"difficulty"` // The level that new games start on
+	Seed int64 `json:"seed"` // 0 picks a random seed
+	Mute bool `json:"mute"`
+	AssetDir string `json:"assetDir"` // Loads assets from this directory instead of the ones embedded in the binary
+	Dev bool `json:"dev"` // Loads assets from disk and reloads them whenever they change
+	Validate bool `json:"-"` // Checks the assets and exits instead of starting the game
+	Debug bool `json:"debug"` // Starts with the physics debug overlay showing
+	Perf bool `json:"perf"` // Starts with the performance overlay showing
+	Profile string `json:"profile"` // Writes CPU and heap profiles and the frame times into this directory
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Width: 1920,
+		Height: 1080,
+		Vsync: true,
+	}
+}
+
+func (c *Config) flagSet(configPath *string) *flag.FlagSet {
+	flags := flag.NewFlagSet("boxlin", flag.ContinueOnError)
+	flags.StringVar(configPath, "config", *configPath, "path to a JSON config file")
+	flags.IntVar(&c.Width, "width", c.Width, "window width")
+	flags.IntVar(&c.Height, "height", c.Height, "window height")
+	flags.BoolVar(&c.Fullscreen, "fullscreen", c.Fullscreen, "start in fullscreen")
+	flags.BoolVar(&c.Vsync, "vsync", c.Vsync, "enable vsync")
+	flags.IntVar(&c.Samples, "samples", c.Samples, "MSAA samples, 0 to disable")
+	flags.IntVar(&c.Difficulty, "difficulty", c.Difficulty, "the level that new games start on")
+	flags.Int64Var(&c.Seed, "seed", c.Seed, "random seed, 0 for a random one")
+	flags.BoolVar(&c.Mute, "mute", c.Mute, "start with the music muted")
+	flags.StringVar(&c.AssetDir, "assets", c.AssetDir, "load assets from this directory instead of the embedded ones")
+	flags.BoolVar(&c.Dev, "dev", c.Dev, "load assets from disk and reload them when they change. Uses ./assets unless -assets is set")
+	flags.BoolVar(&c.Validate, "validate", c.Validate, "check that every asset the game refers to exists, then exit")
+	flags.BoolVar(&c.Debug, "debug", c.Debug, "start with the physics debug overlay showing. F3 toggles it in game")
+	flags.BoolVar(&c.Perf, "perf", c.Perf, "start with the performance overlay showing. F2 toggles it")
+	flags.StringVar(&c.Profile, "profile", c.Profile, "write a CPU profile, a heap profile and a CSV of frame times into this directory")
+	return flags
+}
+
+// Builds the config from the config file and the launch arguments. Flags take priority over the file
+func LoadConfig(args []string) (Config, error) {
+	config := DefaultConfig()
+	configPath := defaultConfigPath()
+
+	// The first pass only finds the config file. The flags are parsed again after loading it so that they win
+	err := config.flagSet(&configPath).Parse(args)
+	if err != nil {
+		return config, err
+	}
+
+	if configPath != "" {
+		dat, err := readConfigFile(configPath)
+		if err != nil {
+			return config, err
+		}
+		if dat != nil {
+			err = json.Unmarshal(dat, &config)
+			if err != nil {
+				return config, fmt.Errorf("invalid config file %s: %w", configPath, err)
+			}
+		}
+	}
+
+	err = config.flagSet(&configPath).Parse(args)
+	if err != nil {
+		return config, err
+	}
+
+	if config.Width <= 0 || config.Height <= 0 {
+		return config, fmt.Errorf("invalid resolution %dx%d", config.Width, config.Height)
+	}
+	if config.Samples < 0 {
+		return config, fmt.Errorf("invalid MSAA samples %d", config.Samples)
+	}
+	if config.Difficulty < 0 {
+		return config, fmt.Errorf("invalid starting difficulty %d", config.Difficulty)
+	}
+	return config, nil
+}
+
+// Seeds the global random source if a seed was given. Otherwise it stays randomly seeded
+func (c Config) ApplySeed() {
+	if c.Seed != 0 {
+		rand.Seed(c.Seed)
+	}
+}
+
+// Returns the filesystem that assets are loaded from. Asset paths always start with assets/, so an override directory takes the place of that folder
+func (c Config) Assets() (fs.FS, error) {
+	if c.AssetDir != "" {
+		return assetDirFS(c.AssetDir)
+	}
+	if c.Dev {
+		return assetDirFS("assets")
+	}
+	return EmbeddedFilesystem, nil
+}
+
+// Serves a filesystem under a path prefix, so that opening "assets/x.png" opens "x.png"
+type prefixFS struct {
+	prefix string
+	fsys fs.FS
+}
+
+func (p prefixFS) Open(name string) (fs.File, error) {
+	if name == p.prefix {
+		return p.fsys.Open(".")
+	}
+	if !strings.HasPrefix(name, p.prefix + "/") {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return p.fsys.Open(strings.TrimPrefix(name, p.prefix + "/"))
+}
@@ -0,0 +1,45 @@
+//go:build !js
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+func launchArgs() []string {
+	return os.Args[1:]
+}
+
+// The config file sits next to the settings file. Returns an empty path if there's no config directory
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "boxlin", "config.json")
+}
+
+// Returns nil data if the config file doesn't exist
+func readConfigFile(path string) ([]byte, error) {
+	dat, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return dat, err
+}
+
+// Wraps the directory so that it answers to the same assets/ paths as the embedded filesystem
+func assetDirFS(dir string) (fs.FS, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("asset override %s is not a directory", dir)
+	}
+	return prefixFS{prefix: "assets", fsys: os.DirFS(dir)}, nil
+}
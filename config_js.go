@@ -0,0 +1,43 @@
+//go:build js
+
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"net/url"
+	"strings"
+	"syscall/js"
+)
+
+// Browsers can't pass command line arguments, so the page's query string is used instead. For example ?seed=5&mute=true. Keys that aren't flags are left out, since pages are often linked to with tracking parameters like utm_source
+func launchArgs() []string {
+	search := js.Global().Get("location").Get("search").String()
+	query, err := url.ParseQuery(strings.TrimPrefix(search, "?"))
+	if err != nil {
+		return nil
+	}
+
+	var configPath string
+	flags := (&Config{}).flagSet(&configPath)
+	args := make([]string, 0, len(query))
+	for key, values := range query {
+		if flags.Lookup(key) == nil { continue }
+		for _, value := range values {
+			args = append(args, "-" + key + "=" + value)
+		}
+	}
+	return args
+}
+
+func defaultConfigPath() string {
+	return ""
+}
+
+func readConfigFile(path string) ([]byte, error) {
+	return nil, errors.New("config files aren't supported in the browser")
+}
+
+func assetDirFS(dir string) (fs.FS, error) {
+	return nil, errors.New("asset overrides aren't supported in the browser")
+}
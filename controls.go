@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/unitoftime/glitch"
+)
+
+var (
+	highlightColor = glitch.FromUint8(0xfa, 0xcb, 0x3e, 0xff)
+	errorColor = glitch.FromUint8(0xd9, 0x57, 0x63, 0xff)
+)
+
+// The screen for rebinding actions. The UI's navigation keys are fixed, so a bad binding can't lock the player out of it
+type ControlsScreen struct {
+	selected int
+	listening bool // Set while waiting for the player to press the new key for the selected action
+
+	message string
+	messageColor glitch.RGBA
+
+	items []string // The rows of the list, reused between frames
+}
+
+func NewControlsScreen() *ControlsScreen {
+	return &ControlsScreen{
+		messageColor: glitch.White,
+	}
+}
+
+func (c *ControlsScreen) setMessage(color glitch.RGBA, format string, args ...any) {
+	c.message = fmt.Sprintf(format, args...)
+	c.messageColor = color
+}
+
+// Declares the screen. Returns true when the player backs out of it
+func (c *ControlsScreen) Update(ui *UI) bool {
+	bindings := ui.input.Bindings()
+	back := ui.win.JustPressed(glitch.KeyEscape) || ui.input.JustPressedButton(GamepadB)
+
+	if c.listening {
+		// Every key and button is read as the new binding, so none of them can navigate
+		ui.IgnoreNavigation()
+		if back {
+			back = false
+			c.listening = false
+			c.setMessage(glitch.White, "")
+		} else {
+			c.readBinding(ui, bindings)
+		}
+	}
+
+	width := 1200.0
+	rowHeight := 55.0
+	messageHeight := 50.0
+	buttonHeight := 100.0
+	gap := 25.0
+	listHeight := float64(ActionLast + 1) * rowHeight
+	area := Anchored(ui.Screen(), AnchorCenter, width, 125 + listHeight + messageHeight + buttonHeight + 3 * gap)
+	column := NewColumn(area, gap)
+
+	ui.Label("Controls", column.Next(125), AnchorCenter, 1, glitch.White)
+
+	c.items = c.items[:0]
+	for action := Action(0); action <= ActionLast; action++ {
+		c.items = append(c.items, fmt.Sprintf("%s: %s", actionNames[action], bindings.Describe(action)))
+	}
+	if row := ui.List(c.items, &c.selected, column.Next(listHeight), rowHeight); row >= 0 {
+		c.listening = true
+		c.setMessage(highlightColor, "Press a new key for %s", actionNames[Action(row)])
+	}
+
+	ui.Label(c.message, column.Next(messageHeight), AnchorCenter, 0.5, c.messageColor)
+
+	buttons := column.Next(buttonHeight)
+	left := buttons.CutLeft((buttons.W() - gap) / 2)
+	buttons.CutLeft(gap)
+
+	if c.listening {
+		if ui.Button("Reset", left) {
+			c.listening = false
+			c.resetAction(bindings, Action(c.selected))
+		}
+		if ui.Button("Cancel", buttons) {
+			c.listening = false
+			c.setMessage(glitch.White, "")
+		}
+		return false
+	}
+
+	if ui.Button("Reset All", left) {
+		defaults := DefaultBindings()
+		for action := Action(0); action <= ActionLast; action++ {
+			bindings.Keys[action] = defaults.Keys[action]
+			bindings.Buttons[action] = defaults.Buttons[action]
+		}
+		c.setMessage(glitch.White, "All controls reset")
+	}
+	if ui.Button("Back", buttons) || back {
+		c.setMessage(glitch.White, "")
+		return true
+	}
+	return false
+}
+
+// Binds the selected action to whichever key or button was pressed this frame, if any
+func (c *ControlsScreen) readBinding(ui *UI, bindings Bindings) {
+	action := Action(c.selected)
+
+	for key := range keyNames {
+		if !ui.win.JustPressed(key) { continue }
+
+		c.listening = false
+		if other, conflict := bindings.KeyConflict(action, key); conflict {
+			c.setMessage(errorColor, "%s is already used by %s", keyNames[key], actionNames[other])
+			return
+		}
+		bindings.Keys[action] = []glitch.Key{key}
+		c.setMessage(glitch.White, "%s bound to %s", actionNames[action], keyNames[key])
+		return
+	}
+
+	for button := GamepadButton(0); button <= GamepadButtonLast; button++ {
+		if !ui.input.JustPressedButton(button) { continue }
+
+		c.listening = false
+		if other, conflict := bindings.ButtonConflict(action, button); conflict {
+			c.setMessage(errorColor, "%s is already used by %s", buttonNames[button], actionNames[other])
+			return
+		}
+		bindings.Buttons[action] = []GamepadButton{button}
+		c.setMessage(glitch.White, "%s bound to %s", actionNames[action], buttonNames[button])
+		return
+	}
+}
+
+// Puts an action back to its default bindings, unless another action has taken one of them since
+func (c *ControlsScreen) resetAction(bindings Bindings, action Action) {
+	defaults := DefaultBindings()
+	for _, key := range defaults.Keys[action] {
+		if other, conflict := bindings.KeyConflict(action, key); conflict {
+			c.setMessage(errorColor, "Can't reset, %s is used by %s", keyNames[key], actionNames[other])
+			return
+		}
+	}
+	for _, button := range defaults.Buttons[action] {
+		if other, conflict := bindings.ButtonConflict(action, button); conflict {
+			c.setMessage(errorColor, "Can't reset, %s is used by %s", buttonNames[button], actionNames[other])
+			return
+		}
+	}
+	bindings.Keys[action] = defaults.Keys[action]
+	bindings.Buttons[action] = defaults.Buttons[action]
+	c.setMessage(glitch.White, "%s reset", actionNames[action])
+}
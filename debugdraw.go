@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/jakecoffman/cp"
+
+	"github.com/unitoftime/glitch"
+)
+
+const (
+	debugLineWidth = 2.0
+	debugContactSize = 8.0
+	debugNormalLength = 20.0
+)
+
+// Debug overlay colors. Bodies are colored by how close they are to settling, since that is what decides when the level ends
+var (
+	debugAwakeColor = glitch.FromUint8(0x99, 0xe5, 0x50, 0xff)
+	debugSettledColor = glitch.FromUint8(0xfa, 0xcb, 0x3e, 0xff)
+	debugSleepingColor = glitch.FromUint8(0x5f, 0xcd, 0xe4, 0xff)
+	debugStaticColor = glitch.FromUint8(0x9b, 0xad, 0xb7, 0xff)
+	debugBBColor = glitch.RGBA{0.3, 0.3, 0.3, 0.3}
+	debugContactColor = glitch.FromUint8(0xd9, 0x57, 0x63, 0xff)
+
+	debugActiveColor = glitch.FromUint8(0x5f, 0xcd, 0xe4, 0xff)
+	debugPegColor = glitch.FromUint8(0xdf, 0x71, 0x26, 0xff)
+	debugAcceptColor = glitch.FromUint8(0x99, 0xe5, 0x50, 0xff)
+)
+
+// Draws the physics shapes, bounding boxes and contacts over the level, along with the state that decides when the level ends
+type PhysicsDebug struct {
+	enabled bool
+	geom *glitch.GeomDraw
+	mesh *glitch.Mesh // Rebuilt every frame
+	text *glitch.Text
+	points []glitch.Vec3 // Reused for polygon outlines
+}
+
+func NewPhysicsDebug(atlas *glitch.Atlas) *PhysicsDebug {
+	geom := glitch.NewGeomDraw()
+	geom.Divisions = 16 // Circles are small, so they don't need many sides
+	return &PhysicsDebug{
+		geom: geom,
+		mesh: glitch.NewMesh(),
+		text: atlas.Text(""),
+	}
+}
+
+func (d *PhysicsDebug) Toggle() {
+	d.enabled = !d.enabled
+}
+
+func (d *PhysicsDebug) Enabled() bool {
+	return d.enabled
+}
+
+// Switches the text to another atlas, such as when the font is reloaded
+func (d *PhysicsDebug) SetAtlas(atlas *glitch.Atlas) {
+	d.text = atlas.Text("")
+}
+
+// Draws the overlay for the level onto pass, and its text into the top right of screen on hudPass
+func (d *PhysicsDebug) Draw(pass, hudPass *glitch.RenderPass, g *Game, screen glitch.Rect) {
+	if !d.enabled { return }
+
+	d.mesh.Clear()
+
+	d.geom.SetColor(debugActiveColor)
+	d.mesh.Append(d.geom.Rectangle(g.activeBounds, debugLineWidth))
+	d.geom.SetColor(debugPegColor)
+	d.mesh.Append(d.geom.Rectangle(g.pegBounds, debugLineWidth))
+	d.geom.SetColor(debugAcceptColor)
+	d.mesh.Append(d.geom.Rectangle(g.acceptBounds, debugLineWidth))
+
+	awake, settled, sleeping := 0, 0, 0
+	g.space.EachBody(func(body *cp.Body) {
+		color := debugStaticColor
+		if body.GetType() == cp.BODY_DYNAMIC {
+			if body.IsSleeping() {
+				color = debugSleepingColor
+				sleeping++
+			} else if body.IdleTime() >= settledIdleTime {
+				color = debugSettledColor
+				settled++
+			} else {
+				color = debugAwakeColor
+				awake++
+			}
+		}
+		d.DrawBody(body, color)
+	})
+	d.mesh.Draw(pass, glitch.Mat4Ident)
+
+	d.text.Set(fmt.Sprintf("Awake: %d\nSettled: %d\nSleeping: %d\nIdle Counter: %d / %d", awake, settled, sleeping, g.idleCounter, settledFrames))
+	bounds := d.text.Bounds().Scaled(0.5)
+	d.text.RectDrawColorMask(hudPass, screen.Unpad(glitch.R(25, 25, 25, 25)).Anchor(bounds, AnchorTopRight), glitch.White)
+}
+
+// Adds the outlines of the body's shapes, their bounding boxes and the body's contacts to the mesh
+func (d *PhysicsDebug) DrawBody(body *cp.Body, color glitch.RGBA) {
+	body.EachShape(func(shape *cp.Shape) {
+		bb := shape.BB()
+		d.geom.SetColor(debugBBColor)
+		d.mesh.Append(d.geom.Rectangle(glitch.R(bb.L, bb.B, bb.R, bb.T), 1))
+
+		d.geom.SetColor(color)
+		switch class := shape.Class.(type) {
+		case *cp.PolyShape:
+			d.points = d.points[:0]
+			for i := 0; i < class.Count(); i++ {
+				v := class.TransformVert(i)
+				d.points = append(d.points, glitch.Vec3{v.X, v.Y, 0})
+			}
+			d.geom.Polygon(d.mesh, d.points, debugLineWidth)
+		case *cp.Circle:
+			c := class.TransformC()
+			d.geom.Circle(d.mesh, glitch.Vec3{c.X, c.Y, 0}, class.Radius(), debugLineWidth)
+		case *cp.Segment:
+			a, b := class.TransformA(), class.TransformB()
+			d.geom.Line(d.mesh, glitch.Vec3{a.X, a.Y, 0}, glitch.Vec3{b.X, b.Y, 0}, 0, 0, debugLineWidth + 2 * class.Radius())
+		}
+	})
+
+	d.geom.SetColor(debugContactColor)
+	body.EachArbiter(func(arb *cp.Arbiter) {
+		contacts := arb.ContactPointSet()
+		for i := 0; i < contacts.Count; i++ {
+			p := contacts.Points[i].PointA
+			d.mesh.Append(d.geom.FillRect(glitch.R(-debugContactSize / 2, -debugContactSize / 2, debugContactSize / 2, debugContactSize / 2).Moved(glitch.Vec2{p.X, p.Y})))
+
+			end := p.Add(contacts.Normal.Mult(debugNormalLength))
+			d.geom.Line(d.mesh, glitch.Vec3{p.X, p.Y, 0}, glitch.Vec3{end.X, end.Y, 0}, 0, 0, debugLineWidth)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"io/fs"
+	"time"
+
+	"github.com/unitoftime/flow/asset"
+	"github.com/unitoftime/glitch"
+	"github.com/unitoftime/packer"
+)
+
+// How often dev mode checks the asset files for changes
+const assetPollInterval = 500 * time.Millisecond
+
+// Polls a set of asset files for changes by comparing their modification times. Used by dev mode, where the assets are loaded from disk
+type AssetWatcher struct {
+	fsys fs.FS
+	modTimes map[string]time.Time
+	lastPoll time.Time
+}
+
+func NewAssetWatcher(fsys fs.FS, paths ...string) *AssetWatcher {
+	w := &AssetWatcher{
+		fsys: fsys,
+		modTimes: make(map[string]time.Time),
+		lastPoll: time.Now(),
+	}
+	for _, path := range paths {
+		w.modTimes[path] = w.modTime(path)
+	}
+	return w
+}
+
+// Returns a zero time for files that can't be read, so that they count as changed once they can be again
+func (w *AssetWatcher) modTime(path string) time.Time {
+	info, err := fs.Stat(w.fsys, path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// Returns the files that changed since the last poll. Most calls return nothing because the files are only checked every assetPollInterval. A nil watcher never reports anything, so it can be polled whether or not dev mode is on
+func (w *AssetWatcher) Changed() map[string]bool {
+	if w == nil { return nil }
+	if time.Since(w.lastPoll) < assetPollInterval { return nil }
+	w.lastPoll = time.Now()
+
+	var changed map[string]bool
+	for path, last := range w.modTimes {
+		modTime := w.modTime(path)
+		if modTime.Equal(last) { continue }
+
+		w.modTimes[path] = modTime
+		if modTime.IsZero() { continue } // Deleted, or in the middle of being rewritten
+
+		if changed == nil {
+			changed = make(map[string]bool)
+		}
+		changed[path] = true
+	}
+	return changed
+}
+
+// Loads the spritesheet again and copies each new sprite over the old sprite of the same name. Bodies, animations and everything else that already holds a sprite pick up the change without having to be rebuilt
+func ReloadSpritesheet(load *asset.Load, filepath string, old *asset.Spritesheet) (*asset.Spritesheet, error) {
+	spritesheet, err := load.Spritesheet(filepath, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// The spritesheet doesn't list its sprites, so read the names out of the file
+	serialized := packer.SerializedSpritesheet{}
+	err = load.Json(filepath, &serialized)
+	if err != nil {
+		return nil, err
+	}
+
+	for name := range serialized.Frames {
+		oldSprite, err := old.Get(name)
+		if err != nil { continue } // A sprite that was just added, so nothing holds it yet
+
+		sprite, err := spritesheet.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		*oldSprite = *sprite
+	}
+	return spritesheet, nil
+}
+
+// Rebuilds the nine panel in place from the named sprite, keeping its border
+func ReloadNinePanel(panel *glitch.NinePanelSprite, spritesheet *asset.Spritesheet, name string) error {
+	reloaded, err := spritesheet.GetNinePanel(name, panel.Border())
+	if err != nil {
+		return err
+	}
+	*panel = *reloaded
+	return nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/unitoftime/glitch"
+	"github.com/unitoftime/glitch/shaders"
+)
+
+const (
+	errorLineLength = 60 // Characters per line before the message wraps
+	errorMaxLines = 8 // The in game error screen cuts the message off after this many lines
+)
+
+// Logs the error and switches to the error screen. The level is left how it was, so the only way out is back to the menu
+func (g *Game) Fail(err error) {
+	fmt.Println("Error:", err)
+	g.err = err
+	g.mode = "error"
+}
+
+// Declares the error screen. Returns true once the player has picked to go back to the menu
+func ErrorScreen(ui *UI, err error) bool {
+	lines := WrapText(err.Error(), errorLineLength)
+	if len(lines) > errorMaxLines {
+		lines = append(lines[:errorMaxLines - 1], "...")
+	}
+
+	width := 1200.0
+	lineHeight := 50.0
+	buttonHeight := 100.0
+	gap := 25.0
+	area := Anchored(ui.Screen(), AnchorCenter, width, 125 + float64(len(lines)) * lineHeight + buttonHeight + 4 * gap)
+	ui.Panel(area, menuShade)
+
+	column := NewColumn(area.Unpad(glitch.R(gap, gap, gap, gap)), gap)
+	ui.Label("Something Went Wrong", column.Next(125), AnchorCenter, 1, highlightColor)
+
+	message := column.Next(float64(len(lines)) * lineHeight)
+	for _, line := range lines {
+		ui.Label(line, message.CutTop(lineHeight), AnchorLeft, 0.5, glitch.White)
+	}
+
+	return ui.Button("Back To Menu", Anchored(column.Next(buttonHeight), AnchorCenter, 500, buttonHeight))
+}
+
+// Shows the error until the window is closed. This is for errors that stop the game from starting, so it only relies on glitch's built in font rather than any of the game's assets
+func ShowFatalError(win *glitch.Window, err error) {
+	atlas, atlasErr := glitch.DefaultAtlas()
+	if atlasErr != nil {
+		fmt.Println("Failed to show error:", atlasErr)
+		return
+	}
+	shader, shaderErr := glitch.NewShader(shaders.SpriteShader)
+	if shaderErr != nil {
+		fmt.Println("Failed to show error:", shaderErr)
+		return
+	}
+	pass := glitch.NewRenderPass(shader)
+	viewport := NewViewport(shader)
+
+	lines := append([]string{"Boxlin failed to start", ""}, WrapText(err.Error(), errorLineLength)...)
+	text := atlas.Text(strings.Join(lines, "\n"))
+
+	for !win.Closed() {
+		viewport.Update(win)
+		screen := viewport.Screen().Unpad(glitch.R(50, 50, 50, 50))
+
+		// Shrink long messages to fit on screen
+		bounds := text.Bounds()
+		scale := 0.75
+		if s := screen.W() / bounds.W(); s < scale {
+			scale = s
+		}
+		if s := screen.H() / bounds.H(); s < scale {
+			scale = s
+		}
+
+		pass.Clear()
+		text.RectDrawColorMask(pass, screen.Anchor(bounds.Scaled(scale), AnchorTopLeft), glitch.White)
+
+		glitch.Clear(win, glitch.Black)
+		camera := viewport.Camera()
+		pass.SetUniform("projection", camera.Projection)
+		pass.SetUniform("view", camera.View)
+		pass.Draw(win)
+
+		win.Update()
+	}
+}
+
+// Splits the text into lines of at most width characters, breaking between words where it can. Existing line breaks are kept
+func WrapText(text string, width int) []string {
+	lines := make([]string, 0)
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			// Words that are too long by themselves get broken up
+			for len(word) > width {
+				if line != "" {
+					lines = append(lines, line)
+					line = ""
+				}
+				lines = append(lines, word[:width])
+				word = word[width:]
+			}
+
+			if line == "" {
+				line = word
+			} else if len(line) + 1 + len(word) <= width {
+				line += " " + word
+			} else {
+				lines = append(lines, line)
+				line = word
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,8 @@
+//go:build !js
+
+package main
+
+// glitch owns the glfw window and doesn't export its joysticks, so desktop builds don't see gamepads yet. The browser build reads them through the Gamepad API instead
+func pollGamepad() GamepadState {
+	return GamepadState{}
+}
@@ -0,0 +1,58 @@
+//go:build js
+
+package main
+
+import (
+	"syscall/js"
+)
+
+// Indices of the buttons in the W3C standard gamepad mapping
+var standardButtons = [GamepadButtonLast + 1]int{
+	GamepadA: 0,
+	GamepadB: 1,
+	GamepadX: 2,
+	GamepadY: 3,
+	GamepadLeftBumper: 4,
+	GamepadRightBumper: 5,
+	GamepadBack: 8,
+	GamepadStart: 9,
+	GamepadDpadUp: 12,
+	GamepadDpadDown: 13,
+	GamepadDpadLeft: 14,
+	GamepadDpadRight: 15,
+}
+
+// Reads the first connected gamepad through the browser's Gamepad API
+// See: https://developer.mozilla.org/en-US/docs/Web/API/Gamepad_API
+func pollGamepad() GamepadState {
+	state := GamepadState{}
+
+	navigator := js.Global().Get("navigator")
+	if navigator.Get("getGamepads").IsUndefined() {
+		return state
+	}
+
+	pads := navigator.Call("getGamepads")
+	for i := 0; i < pads.Length(); i++ {
+		pad := pads.Index(i)
+		if pad.IsNull() || pad.IsUndefined() { continue }
+		if !pad.Get("connected").Bool() { continue }
+		if pad.Get("mapping").String() != "standard" { continue }
+
+		state.Connected = true
+		buttons := pad.Get("buttons")
+		for button, index := range standardButtons {
+			if index < buttons.Length() {
+				state.Buttons[button] = buttons.Index(index).Get("pressed").Bool()
+			}
+		}
+
+		axes := pad.Get("axes")
+		if axes.Length() > 0 {
+			state.LeftX = axes.Index(0).Float()
+		}
+		return state
+	}
+
+	return state
+}
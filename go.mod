@@ -8,6 +8,7 @@ require (
 	github.com/jakecoffman/cp v1.2.1
 	github.com/unitoftime/flow v0.0.0-20230428154137-9e2b867b0d21
 	github.com/unitoftime/glitch v0.0.0-20230501123718-8feee72044d9
+	github.com/unitoftime/packer v0.0.0-20221103211833-11c7601528ba
 )
 
 require (
@@ -18,7 +19,6 @@ require (
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
 	github.com/hashicorp/golang-lru/v2 v2.0.2 // indirect
 	github.com/unitoftime/ecs v0.0.0-20230420114309-19b152b63ee0 // indirect
-	github.com/unitoftime/packer v0.0.0-20221103211833-11c7601528ba // indirect
 	golang.org/x/image v0.7.0 // indirect
 	golang.org/x/sys v0.7.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
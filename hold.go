@@ -0,0 +1,66 @@
+package main
+
+import (
+	"math"
+
+	"github.com/unitoftime/glitch"
+)
+
+// How far the held package turns for each notch of the scroll wheel
+const scrollRotation = math.Pi / 12
+
+// How far left of the level the hold slot and the upcoming packages are drawn. The hold slot sits at the top of the column with the queue below it
+const queueOffset = 300.0
+
+func (g *Game) RotateHeld(angle float64) {
+	if g.heldShape == nil { return }
+
+	g.heldAngle = math.Mod(g.heldAngle + angle, 2 * math.Pi)
+}
+
+// Swaps the held package with the one in the hold slot. This can only be done once per drop so the player can't stall forever
+func (g *Game) SwapHeld() error {
+	if g.heldShape == nil { return nil }
+	if g.holdUsed { return nil }
+
+	current := g.heldPackage
+	g.space.RemoveShape(g.heldShape)
+	g.space.RemoveBody(g.heldShape.Body())
+
+	var err error
+	if g.holdPackage == "" {
+		g.holdPackage = current
+		g.heldShape, err = g.GetNextPackage()
+	} else {
+		pkg := g.holdPackage
+		g.holdPackage = current
+		g.heldShape, err = g.SpawnPackage(pkg)
+	}
+	if err != nil {
+		return err
+	}
+
+	g.holdUsed = true
+	return nil
+}
+
+// Draws the hold slot above the upcoming packages
+func (g *Game) DrawHoldPackage(pass *glitch.RenderPass) error {
+	if g.holdPackage == "" { return nil }
+
+	sprite, err := g.spritesheet.Get(g.holdPackage)
+	if err != nil {
+		return err
+	}
+
+	// Dim the package while it can't be swapped back out
+	color := glitch.White
+	if g.holdUsed {
+		color = glitch.FromUint8(0x80, 0x80, 0x80, 0xff)
+	}
+
+	mat := glitch.Mat4Ident
+	mat.Translate(g.levelBounds.Min[0] - queueOffset, g.dropHeight - 100, 0)
+	sprite.DrawColorMask(pass, mat, color)
+	return nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"github.com/unitoftime/glitch"
+)
+
+type Action uint8
+const (
+	ActionLeft Action = iota
+	ActionRight
+	ActionDrop
+	ActionRotateLeft
+	ActionRotateRight
+	ActionHold
+	ActionPreview
+	ActionPause
+	ActionMute
+	ActionConfirm // Activates the focused menu item
+	ActionControls // Opens the controls screen from the menu
+	ActionLast = ActionControls
+)
+
+// Buttons laid out like the W3C standard gamepad mapping, which glfw also follows
+type GamepadButton uint8
+const (
+	GamepadA GamepadButton = iota
+	GamepadB
+	GamepadX
+	GamepadY
+	GamepadLeftBumper
+	GamepadRightBumper
+	GamepadBack
+	GamepadStart
+	GamepadDpadUp
+	GamepadDpadRight
+	GamepadDpadDown
+	GamepadDpadLeft
+	GamepadButtonLast = GamepadDpadLeft
+)
+
+type GamepadState struct {
+	Connected bool
+	Buttons [GamepadButtonLast + 1]bool
+	LeftX float64 // The left stick's horizontal axis from -1 to 1
+}
+
+const (
+	stickDeadzone = 0.2
+	aimSpeed = 900.0 // How fast the keyboard and gamepad move the aim, in world units per second
+)
+
+type Bindings struct {
+	Keys map[Action][]glitch.Key
+	Buttons map[Action][]GamepadButton
+}
+
+func DefaultBindings() Bindings {
+	return Bindings{
+		Keys: map[Action][]glitch.Key{
+			ActionLeft: {glitch.KeyLeft, glitch.KeyA},
+			ActionRight: {glitch.KeyRight, glitch.KeyD},
+			ActionDrop: {glitch.MouseButtonLeft, glitch.KeySpace, glitch.KeyDown},
+			ActionRotateLeft: {glitch.KeyQ},
+			ActionRotateRight: {glitch.KeyE, glitch.KeyUp},
+			ActionHold: {glitch.KeyC, glitch.MouseButtonRight},
+			ActionPreview: {glitch.KeyP},
+			ActionPause: {glitch.KeyEscape},
+			ActionMute: {glitch.KeyM},
+			ActionConfirm: {glitch.KeySpace, glitch.KeyEnter},
+			ActionControls: {glitch.KeyTab},
+		},
+		Buttons: map[Action][]GamepadButton{
+			ActionLeft: {GamepadDpadLeft},
+			ActionRight: {GamepadDpadRight},
+			ActionDrop: {GamepadA},
+			ActionRotateLeft: {GamepadLeftBumper},
+			ActionRotateRight: {GamepadRightBumper},
+			ActionHold: {GamepadX},
+			ActionPreview: {GamepadY},
+			ActionPause: {GamepadStart},
+			ActionMute: {GamepadBack},
+			ActionConfirm: {GamepadA, GamepadStart},
+			ActionControls: {GamepadY},
+		},
+	}
+}
+
+// Maps the mouse, keyboard and gamepad onto game actions
+type Input struct {
+	win *glitch.Window
+	bindings Bindings
+
+	pad, lastPad GamepadState
+	mouse, lastMouse glitch.Vec2
+	touch TouchState
+	touchSeen bool // Set once the player has used a touch screen, which switches the menus over to buttons
+}
+
+func NewInput(win *glitch.Window, bindings Bindings) *Input {
+	return &Input{
+		win: win,
+		bindings: bindings,
+	}
+}
+
+// Must be called once per frame before any actions are checked
+func (i *Input) Update() {
+	i.lastPad = i.pad
+	i.pad = pollGamepad()
+
+	i.lastMouse = i.mouse
+	x, y := i.win.MousePosition()
+	i.mouse = glitch.Vec2{x, y}
+
+	i.touch = pollTouch(i.win)
+	if i.touch.Started {
+		i.touchSeen = true
+	}
+}
+
+func (i *Input) Touch() TouchState {
+	return i.touch
+}
+
+func (i *Input) UsingTouch() bool {
+	return i.touchSeen
+}
+
+func (i *Input) Bindings() Bindings {
+	return i.bindings
+}
+
+func (i *Input) SetBindings(bindings Bindings) {
+	i.bindings = bindings
+}
+
+func (i *Input) JustPressed(action Action) bool {
+	for _, key := range i.bindings.Keys[action] {
+		if i.win.JustPressed(key) {
+			return true
+		}
+	}
+	for _, button := range i.bindings.Buttons[action] {
+		if i.pad.Buttons[button] && !i.lastPad.Buttons[button] {
+			return true
+		}
+	}
+	return false
+}
+
+func (i *Input) JustPressedButton(button GamepadButton) bool {
+	return i.pad.Buttons[button] && !i.lastPad.Buttons[button]
+}
+
+func (i *Input) Pressed(action Action) bool {
+	for _, key := range i.bindings.Keys[action] {
+		if i.win.Pressed(key) {
+			return true
+		}
+	}
+	for _, button := range i.bindings.Buttons[action] {
+		if i.pad.Buttons[button] {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true if the mouse moved this frame, in which case it takes over aiming
+func (i *Input) MouseMoved() bool {
+	return i.mouse != i.lastMouse
+}
+
+// Returns how far to push the aim this frame from -1 to 1, combining the move actions and the left stick
+func (i *Input) AimAxis() float64 {
+	axis := 0.0
+	if i.Pressed(ActionLeft) {
+		axis -= 1
+	}
+	if i.Pressed(ActionRight) {
+		axis += 1
+	}
+
+	if i.pad.LeftX > stickDeadzone || i.pad.LeftX < -stickDeadzone {
+		axis += i.pad.LeftX
+	}
+
+	if axis > 1 {
+		axis = 1
+	} else if axis < -1 {
+		axis = -1
+	}
+	return axis
+}
+
+// Returns the scroll wheel movement, which rotates packages in free increments
+func (i *Input) Scroll() float64 {
+	_, y := i.win.MouseScroll()
+	return y
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/unitoftime/glitch"
+)
+
+// A peg layout returns up to num peg positions inside of bounds, spaced roughly spacing apart
+type PegLayout func(bounds glitch.Rect, spacing float64, num int) []glitch.Vec2
+
+var pegLayouts = map[string]PegLayout{
+	"random": RandomLayout,
+	"triangle": TriangleLayout,
+	"staggered": StaggeredLayout,
+	"funnel": FunnelLayout,
+	"mirrored": MirroredLayout,
+	"spiral": SpiralLayout,
+}
+
+// A table of things to roll from once the difficulty reaches minDifficulty
+type difficultyBand[T any] struct {
+	minDifficulty int
+	table *RngTable[T]
+}
+
+// Rolls from the hardest band that the difficulty has reached. Bands must be sorted from hardest to easiest
+func rollBand[T any](bands []difficultyBand[T], difficulty int, fallback T) T {
+	for _, band := range bands {
+		if difficulty >= band.minDifficulty {
+			return band.table.Roll()
+		}
+	}
+	return fallback
+}
+
+var layoutBands = []difficultyBand[string]{
+	{10, NewRngTable(
+		NewRngItem(10, "random"),
+		NewRngItem(10, "mirrored"),
+		NewRngItem(10, "funnel"),
+		NewRngItem(10, "spiral"),
+	)},
+	{5, NewRngTable(
+		NewRngItem(10, "random"),
+		NewRngItem(10, "staggered"),
+		NewRngItem(10, "funnel"),
+		NewRngItem(10, "mirrored"),
+	)},
+	{0, NewRngTable(
+		NewRngItem(10, "random"),
+		NewRngItem(10, "triangle"),
+		NewRngItem(10, "staggered"),
+	)},
+}
+
+// Returns a random peg layout name from the difficulty band. Used when the level data doesn't pick a layout
+func LayoutForLevel(difficulty int) string {
+	return rollBand(layoutBands, difficulty, "random")
+}
+
+// Uniformly scattered pegs using Poisson-disc sampling
+func RandomLayout(bounds glitch.Rect, spacing float64, num int) []glitch.Vec2 {
+	points := PoissonDisc(bounds, spacing)
+	rand.Shuffle(len(points), func(i, j int) {
+		points[i], points[j] = points[j], points[i]
+	})
+	return truncatePoints(points, num)
+}
+
+// The classic plinko triangle, with one peg at the top and each row below it getting one wider. The rows are squeezed together when they wouldn't otherwise fit, so that every peg gets placed
+func TriangleLayout(bounds glitch.Rect, spacing float64, num int) []glitch.Vec2 {
+	rows := 1
+	for rows * (rows + 1) / 2 < num {
+		rows++
+	}
+
+	rowHeight := spacing * math.Sqrt(3) / 2
+	colWidth := spacing
+	if rows > 1 {
+		rowHeight = math.Min(rowHeight, bounds.H() / float64(rows - 1))
+		colWidth = math.Min(colWidth, bounds.W() / float64(rows - 1))
+	}
+	center := bounds.Center()
+
+	points := make([]glitch.Vec2, 0, rows * (rows + 1) / 2)
+	for row := 0; row < rows; row++ {
+		y := bounds.Max[1] - float64(row) * rowHeight
+		startX := center[0] - float64(row) * colWidth / 2
+		for i := 0; i <= row; i++ {
+			points = append(points, glitch.Vec2{startX + float64(i) * colWidth, y})
+		}
+	}
+	return truncatePoints(points, num)
+}
+
+// Rows of pegs filling the bounds, where every other row is shifted by half a space
+func StaggeredLayout(bounds glitch.Rect, spacing float64, num int) []glitch.Vec2 {
+	rowHeight := spacing * math.Sqrt(3) / 2
+	center := bounds.Center()
+	cols := int(bounds.W() / spacing)
+
+	points := make([]glitch.Vec2, 0)
+	for row := 0; ; row++ {
+		y := bounds.Max[1] - float64(row) * rowHeight
+		if y < bounds.Min[1] { break }
+
+		offset := 0.0
+		if row % 2 == 1 {
+			offset = spacing / 2
+		}
+		startX := center[0] - float64(cols) * spacing / 2 + offset
+		for i := 0; i <= cols; i++ {
+			x := startX + float64(i) * spacing
+			if !bounds.Contains(x, y) { continue }
+			points = append(points, glitch.Vec2{x, y})
+		}
+	}
+	return truncatePoints(points, num)
+}
+
+// Two diagonal lines of pegs that narrow down into a gap in the middle of the bounds
+func FunnelLayout(bounds glitch.Rect, spacing float64, num int) []glitch.Vec2 {
+	center := bounds.Center()
+	gap := spacing
+
+	// Walk each arm inward from the top corners, alternating sides so that a short count stays symmetric
+	left := glitch.Vec2{bounds.Min[0], bounds.Max[1]}
+	right := glitch.Vec2{bounds.Max[0], bounds.Max[1]}
+	leftEnd := glitch.Vec2{center[0] - gap, bounds.Min[1]}
+	dir := leftEnd.Sub(left)
+	length := dir.Len()
+	steps := int(length / spacing)
+
+	points := make([]glitch.Vec2, 0)
+	for i := 0; i <= steps; i++ {
+		t := float64(i) * spacing / length
+		dx := dir[0] * t
+		dy := dir[1] * t
+		points = append(points,
+			glitch.Vec2{left[0] + dx, left[1] + dy},
+			glitch.Vec2{right[0] - dx, right[1] + dy},
+		)
+	}
+	return truncatePoints(points, num)
+}
+
+// Random pegs on the left half, mirrored onto the right half
+func MirroredLayout(bounds glitch.Rect, spacing float64, num int) []glitch.Vec2 {
+	center := bounds.Center()
+
+	// Keep half a space from the mirror line so reflected pegs never overlap their twin
+	half := glitch.R(bounds.Min[0], bounds.Min[1], center[0] - spacing / 2, bounds.Max[1])
+	points := PoissonDisc(half, spacing)
+	rand.Shuffle(len(points), func(i, j int) {
+		points[i], points[j] = points[j], points[i]
+	})
+
+	mirrored := make([]glitch.Vec2, 0, 2 * len(points))
+	for _, p := range points {
+		mirrored = append(mirrored, p, glitch.Vec2{2 * center[0] - p[0], p[1]})
+	}
+
+	// Drop pairs, not single pegs, so the result stays symmetric
+	if num % 2 == 1 {
+		num--
+	}
+	return truncatePoints(mirrored, num)
+}
+
+// An archimedean spiral winding out from the center of the bounds
+func SpiralLayout(bounds glitch.Rect, spacing float64, num int) []glitch.Vec2 {
+	center := bounds.Center()
+
+	// Each loop of the spiral moves out by one space
+	growth := spacing / (2 * math.Pi)
+	maxRadius := math.Hypot(bounds.W(), bounds.H()) / 2
+
+	// Start one loop out so the first pegs aren't bunched up on the center
+	points := make([]glitch.Vec2, 0)
+	theta := 2 * math.Pi
+	for {
+		radius := growth * theta
+		if radius > maxRadius { break }
+
+		p := glitch.Vec2{center[0] + radius * math.Cos(theta), center[1] + radius * math.Sin(theta)}
+		if bounds.Contains(p[0], p[1]) {
+			points = append(points, p)
+		}
+
+		// Step by roughly one space of arc length
+		theta += spacing / radius
+	}
+	return truncatePoints(points, num)
+}
+
+func truncatePoints(points []glitch.Vec2, num int) []glitch.Vec2 {
+	if len(points) > num {
+		return points[:num]
+	}
+	return points
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"github.com/jakecoffman/cp"
+
+	"github.com/unitoftime/flow/asset"
+	"github.com/unitoftime/glitch"
+)
+
+type LevelData struct {
+	Levels []Level `json:"levels"`
+}
+
+// Hand authored overrides for a single level. Anything left empty falls back to the procedural defaults
+type Level struct {
+	Level int `json:"level"`
+	Layout string `json:"layout"`
+	Conveyors []Conveyor `json:"conveyors"`
+	Bins []Bin `json:"bins"`
+}
+
+type Conveyor struct {
+	// The min x, min y, max x, max y of the belt as fractions of the level bounds
+	Rect [4]float64 `json:"rect"`
+	// How fast the belt moves packages along it. Positive moves them right, negative moves them left
+	Speed float64 `json:"speed"`
+}
+
+// Returns the belt rect in world coordinates
+func (c Conveyor) Bounds(levelBounds glitch.Rect) glitch.Rect {
+	return glitch.R(
+		levelBounds.Min[0] + c.Rect[0] * levelBounds.W(),
+		levelBounds.Min[1] + c.Rect[1] * levelBounds.H(),
+		levelBounds.Min[0] + c.Rect[2] * levelBounds.W(),
+		levelBounds.Min[1] + c.Rect[3] * levelBounds.H(),
+	).Norm()
+}
+
+// Loads the level data file and indexes it by level number
+func LoadLevels(load *asset.Load, filepath string) (map[int]Level, error) {
+	dat := LevelData{}
+	err := load.Json(filepath, &dat)
+	if err != nil {
+		return nil, err
+	}
+
+	levels := make(map[int]Level)
+	for _, level := range dat.Levels {
+		levels[level.Level] = level
+	}
+	return levels, nil
+}
+
+var conveyorColor = glitch.FromUint8(0xc8, 0x8a, 0x4a, 0xff)
+
+func (g *Game) AddConveyor(conveyor Conveyor) error {
+	rect := conveyor.Bounds(g.levelBounds)
+
+	ninePanel, err := g.WallPanel()
+	if err != nil {
+		return err
+	}
+
+	s := NewSprite(nil)
+	s.ninePanel = ninePanel
+	s.rect = glitch.R(-rect.W()/2, -rect.H()/2, rect.W()/2, rect.H()/2)
+	s.color = conveyorColor
+
+	shape := makeConveyor(s, rect, conveyor.Speed)
+	g.space.AddBody(shape.Body())
+	g.space.AddShape(shape)
+	return nil
+}
+
+func makeConveyor(sprite Sprite, rect glitch.Rect, speed float64) *cp.Shape {
+	shape := makeWall(sprite, rect)
+
+	// The surface velocity is what the friction solver tries to match, so anything resting on the belt gets dragged along at this speed
+	setSurfaceV(shape, cp.Vector{speed, 0})
+	shape.SetFriction(1)
+
+	return shape
+}
@@ -11,11 +11,14 @@ package main
 // - [ ] Submit???
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"time"
 	"math"
 	"math/rand"
 	"embed"
+	"os"
 	"unicode"
 
 	"github.com/jakecoffman/cp"
@@ -27,142 +30,537 @@ import (
 
 	"github.com/unitoftime/glitch"
 	"github.com/unitoftime/glitch/shaders"
+
+	"github.com/unitoftime/boxlin/aseprite"
 )
 
+//go:generate go run ./cmd/assetgen
+
 //go:embed assets/*
 var EmbeddedFilesystem embed.FS
 
 const (
 	Gravity = -9.81
+	settledIdleTime = 0.1 // A body counts as settled once it has been still for this long, in seconds
+	settledFrames = 100 // The level ends after everything has stayed settled for this many frames
+)
+
+// Paths inside of the asset filesystem
+const (
+	spritesheetPath = "assets/spritesheet.json"
+	spritesheetImagePath = "assets/spritesheet.png"
+	animationsPath = "assets/animations.json"
+	fontPath = "assets/ThaleahFat.ttf"
+	musicPath = "assets/bg.mp3"
+	levelsPath = "assets/levels.json"
 )
 
 func main() {
-	glitch.Run(run)
+	config, err := LoadConfig(launchArgs())
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+
+	// Validating doesn't need a window, so it happens before one is opened
+	if config.Validate {
+		filesystem, err := config.Assets()
+		if err == nil {
+			err = ValidateAssets(filesystem)
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Println("Assets are valid")
+		return
+	}
+
+	glitch.Run(func() {
+		run(config)
+	})
 }
 
-func run() {
-	win, err := glitch.NewWindow(1920, 1080, "Boxlin", glitch.WindowConfig{
-		Vsync: true,
-		Fullscreen: false,
-		// Samples: 4,
+func run(config Config) {
+	config.ApplySeed()
+
+	win, err := glitch.NewWindow(config.Width, config.Height, "Boxlin", glitch.WindowConfig{
+		Vsync: config.Vsync,
+		Fullscreen: config.Fullscreen,
+		Samples: config.Samples,
 	})
 	if err != nil {
-		panic(err)
+		// There's nowhere to show the error without a window
+		fmt.Println("Failed to open window:", err)
+		return
 	}
 
-	load := asset.NewLoad(EmbeddedFilesystem)
-	spritesheet, err := load.Spritesheet("assets/spritesheet.json", false)
+	err = runGame(win, config)
 	if err != nil {
-		panic(err)
+		fmt.Println("Error:", err)
+		ShowFatalError(win, err)
 	}
+}
 
-	font, err := load.Font("assets/ThaleahFat.ttf", 64)
+// Loads everything and runs the game until the window is closed. Errors that happen once the game is running are shown on the error screen, so this only returns the ones that stop it from starting at all
+func runGame(win *glitch.Window, config Config) error {
+	filesystem, err := config.Assets()
 	if err != nil {
-		panic(err)
+		return err
 	}
-	runes := make([]rune, unicode.MaxASCII - 32)
-	for i := range runes {
-		runes[i] = rune(32 + i)
+	err = ValidateAssets(filesystem)
+	if err != nil {
+		return fmt.Errorf("invalid assets:\n%w", err)
+	}
+	load := asset.NewLoad(filesystem)
+	spritesheet, err := load.Spritesheet(spritesheetPath, false)
+	if err != nil {
+		return err
+	}
+
+	atlas, err := LoadAtlas(load, fontPath)
+	if err != nil {
+		return err
 	}
-	atlas := glitch.NewAtlas(font, runes, true, 0)
 
 	healthText := atlas.Text(" Health: 10")
-	menuText := atlas.Text(" Press Space To Play!")
-	muteText := atlas.Text(" Press M To Mute")
-	recordText := atlas.Text("High Score: 0")
+	holdText := atlas.Text("Hold")
+	physicsDebug := NewPhysicsDebug(atlas)
+	if config.Debug {
+		physicsDebug.Toggle()
+	}
+	perfHUD := NewPerfHUD(atlas)
+	if config.Perf {
+		perfHUD.Toggle()
+	}
+
+	var profiler *Profiler
+	if config.Profile != "" {
+		profiler, err = StartProfiler(config.Profile)
+		if err != nil {
+			return fmt.Errorf("failed to start profiling: %w", err)
+		}
+		defer func() {
+			if err := profiler.Stop(); err != nil {
+				fmt.Println("Failed to write profile:", err)
+			}
+		}()
+	}
 
 	shader, err := glitch.NewShader(shaders.SpriteShader)
-	if err != nil { panic(err) }
+	if err != nil {
+		return err
+	}
 	pass := glitch.NewRenderPass(shader)
+	hudPass := glitch.NewRenderPass(shader) // Drawn without the camera effects, so that the HUD doesn't shake
 
-	camera := glitch.NewCameraOrtho()
+	viewport := NewViewport(shader)
 
 	levelBounds := glitch.R(0, 0, 900, 700).CenterAt(glitch.Vec2{}).Moved(glitch.Vec2{0, -100})
 
 	game := NewGame(win, levelBounds, spritesheet)
+	game.levels, err = LoadLevels(load, levelsPath)
+	if err != nil {
+		return err
+	}
+	game.animations, err = LoadAnimations(load, animationsPath)
+	if err != nil {
+		return err
+	}
+	err = game.BuildAnimations()
+	if err != nil {
+		return err
+	}
 
 	game.mode = "menu"
 
+	settings, err := LoadSettings()
+	if err != nil {
+		fmt.Println("Failed to load settings:", err)
+	}
+	game.showPreview = settings.ShowPreview
+	game.effects.Apply(settings)
+	game.startDifficulty = config.Difficulty
+	// Muting from the command line sticks like muting in game would
+	if config.Mute {
+		settings.Muted = true
+	}
+
+	volume, muted := settings.Volume, settings.Muted
 	go func() {
-		game.player = NewAudioPlayer()
-		bgMusic := LoadMp3(load, "assets/bg.mp3")
+		// The game still works without sound, so audio problems are only logged
+		player, err := NewAudioPlayer()
+		if err != nil {
+			fmt.Println("Failed to start audio:", err)
+			return
+		}
+		player.SetVolume(volume)
+		player.SetMuted(muted)
+		game.player = player
+		bgMusic, err := LoadMp3(load, musicPath)
+		if err != nil {
+			fmt.Println("Failed to load music:", err)
+			return
+		}
 		game.player.Play(bgMusic)
 	}()
 	// game.hitSound = LoadMp3(load, "assets/hit.mp3")
 	// game.player.Play(game.hitSound)
 
-	game.ResetLevel()
+	err = game.ResetLevel()
+	if err != nil {
+		return err
+	}
+
+	background, err := spritesheet.Get(backgroundSprite)
+	if err != nil {
+		return err
+	}
+	packingLine := NewAnimatedSprite(game.packingLineAnimation, "run")
+
+	bindings, err := BindingsFromNames(settings.Bindings)
+	if err != nil {
+		fmt.Println("Failed to load bindings:", err)
+	}
+	for _, conflict := range bindings.Conflicts() {
+		fmt.Println("Binding conflict:", conflict)
+	}
+	input := NewInput(win, bindings)
+	controlsScreen := NewControlsScreen()
 
-	packingLine, err := spritesheet.Get("background-0.png")
-	// packingLine, err := spritesheet.Get("packing-line-0.png")
-	// border := 0.0
-	// packingLine, err := spritesheet.GetNinePanel("packing-line-0.png", glitch.R(border, border, border, border))
-	if err != nil { panic(err) }
+	buttonPanel, err := game.WallPanel()
+	if err != nil {
+		return err
+	}
+	rotateButton := NewTouchButton(atlas, "Rotate")
+	holdButton := NewTouchButton(atlas, "Hold")
+	pauseButton := NewTouchButton(atlas, "Menu")
+	gameTouch := NewTouchControls(rotateButton, holdButton, pauseButton)
+	resultsScreen := NewResultsScreen("Level Complete", "", "Continue")
+	gameOverScreen := NewResultsScreen("Game Over", "New Record!", "Retry", "Menu")
+	controlsReturn := "menu" // The mode to go back to when the controls screen closes
+	settingsReturn := "menu"
+	ui := NewUI(win, input, atlas, buttonPanel, shader)
+	uiMode := game.mode // The mode that the UI's focus belongs to
+
+	var watcher *AssetWatcher
+	if config.Dev {
+		watcher = NewAssetWatcher(filesystem, spritesheetPath, spritesheetImagePath, animationsPath, fontPath, musicPath, levelsPath)
+	}
 
 	// dt := 16 * time.Millisecond
-	// frameStart := time.Now()
+	frameStart := time.Now()
 
 	for !win.Closed() {
-		camera.SetOrtho2D(win.Bounds())
-		camPos := win.Bounds().Center()
-		camera.SetView2D(-camPos[0], -camPos[1], 1.0, 1.0)
+		frameDt := time.Since(frameStart).Seconds()
+		frameStart = time.Now()
+		physicsTime := 0.0
+
+		input.Update()
+
+		if changed := watcher.Changed(); len(changed) > 0 {
+			// Problems are only reported in dev mode, since anything that is broken keeps its last good version
+			if err := ValidateAssets(filesystem); err != nil {
+				fmt.Println("Asset problems:")
+				fmt.Println(err)
+			}
+
+			if changed[spritesheetPath] || changed[spritesheetImagePath] || changed[animationsPath] {
+				// The asset pipeline writes the animations along with the spritesheet, and the background and wall panels depend on both
+				animations, err := LoadAnimations(load, animationsPath)
+				if err == nil {
+					game.animations = animations
+				} else {
+					fmt.Println("Failed to reload animations:", err)
+				}
+
+				reloaded, err := ReloadSpritesheet(load, spritesheetPath, game.spritesheet)
+				if err == nil {
+					game.spritesheet = reloaded
+					game.space.EachBody(func(body *cp.Body) {
+						// Every nine panel in the level is cut from the wall sprite
+						if panel := body.UserData.(Sprite).ninePanel; panel != nil {
+							if err := ReloadNinePanel(panel, game.spritesheet, wallSprite); err != nil {
+								fmt.Println("Failed to reload nine panel:", err)
+							}
+						}
+					})
+					if panel, err := game.WallPanel(); err == nil {
+						*buttonPanel = *panel
+					}
+					if sprite, err := game.spritesheet.Get(backgroundSprite); err == nil {
+						background = sprite
+					}
+					if err := game.BuildAnimations(); err == nil {
+						packingLine = NewAnimatedSprite(game.packingLineAnimation, "run")
+					} else {
+						fmt.Println("Failed to rebuild animations:", err)
+					}
+				} else {
+					fmt.Println("Failed to reload spritesheet:", err)
+				}
+			}
+			if changed[fontPath] {
+				reloaded, err := LoadAtlas(load, fontPath)
+				if err == nil {
+					atlas = reloaded
+					healthText = atlas.Text(" Health: 10")
+					holdText = atlas.Text("Hold")
+					rotateButton.SetAtlas(atlas)
+					holdButton.SetAtlas(atlas)
+					pauseButton.SetAtlas(atlas)
+					ui.SetAtlas(atlas)
+					physicsDebug.SetAtlas(atlas)
+					perfHUD.SetAtlas(atlas)
+				} else {
+					fmt.Println("Failed to reload font:", err)
+				}
+			}
+			if changed[musicPath] && game.player != nil {
+				music, err := LoadMp3(load, musicPath)
+				if err == nil {
+					game.player.Play(music)
+				} else {
+					fmt.Println("Failed to reload music:", err)
+				}
+			}
+			if changed[levelsPath] {
+				levels, err := LoadLevels(load, levelsPath)
+				if err == nil {
+					game.levels = levels
+					// Restart the level so that the changes show up straight away. The rest of the run carries on
+					if game.mode == "game" || game.mode == "paused" {
+						if err := game.ResetLevel(); err != nil {
+							game.Fail(err)
+						}
+					}
+				} else {
+					fmt.Println("Failed to reload levels:", err)
+				}
+			}
+		}
+
+		viewport.Update(win)
+		camera := viewport.Camera()
 
 		mouseX, mouseY := win.MousePosition()
-		game.mousePos = camera.Unproject(glitch.Vec3{mouseX, mouseY, 0})
+		game.mousePos = viewport.Unproject(mouseX, mouseY)
+
+		touch := input.Touch()
+		touchPos := viewport.Unproject(touch.Pos[0], touch.Pos[1])
+
+		// The screen in world coordinates
+		screen := viewport.Screen()
+		// The in game buttons stack up in the empty space to the right of the level, below the hold slot
+		rotateButton.SetRect(glitch.R(game.levelBounds.Max[0] + 100, game.levelBounds.Min[1] + 330, game.levelBounds.Max[0] + 450, game.levelBounds.Min[1] + 450))
+		holdButton.SetRect(glitch.R(game.levelBounds.Max[0] + 100, game.levelBounds.Min[1] + 165, game.levelBounds.Max[0] + 450, game.levelBounds.Min[1] + 285))
+		pauseButton.SetRect(glitch.R(game.levelBounds.Max[0] + 100, game.levelBounds.Min[1], game.levelBounds.Max[0] + 450, game.levelBounds.Min[1] + 120))
 
-		if win.JustPressed(glitch.KeyM) {
-			game.player.TogglePlayPause()
+		if game.mode != uiMode {
+			ui.ResetFocus()
+			uiMode = game.mode
+		}
+		ui.Begin(screen, game.mousePos, touch, touchPos)
+
+		toggleMute := input.JustPressed(ActionMute)
+		if win.JustPressed(glitch.KeyF3) {
+			physicsDebug.Toggle()
+		}
+		if win.JustPressed(glitch.KeyF2) {
+			perfHUD.Toggle()
 		}
 
 		if game.mode == "menu" {
-			if win.JustPressed(glitch.KeySpace) {
-				game.ResetGame()
+			switch MainMenu(ui, input.Bindings(), game.record) {
+			case MenuPlay:
 				game.mode = "game"
+				if err := game.ResetGame(); err != nil {
+					game.Fail(err)
+				}
+			case MenuSettings:
+				game.mode = "settings"
+				settingsReturn = "menu"
+			case MenuControls:
+				game.mode = "controls"
+				controlsReturn = "menu"
+			case MenuMute:
+				toggleMute = true
+			}
+			if input.JustPressed(ActionControls) {
+				game.mode = "controls"
+				controlsReturn = "menu"
 			}
 			// if win.JustPressed(glitch.KeyEscape) {
 			// 	win.Close()
 			// }
-		} else if game.mode == "game" {
-			if win.JustPressed(glitch.KeyEscape) {
+		} else if game.mode == "settings" {
+			picked, changed := SettingsMenu(ui, &settings)
+			if changed && game.player != nil {
+				game.player.SetVolume(settings.Volume)
+				game.player.SetMuted(settings.Muted)
+			}
+			game.showPreview = settings.ShowPreview
+			game.effects.Apply(settings)
+
+			switch picked {
+			case SettingsControls:
+				game.mode = "controls"
+				controlsReturn = "settings"
+			case SettingsBack:
+				err := SaveSettings(settings)
+				if err != nil {
+					fmt.Println("Failed to save settings:", err)
+				}
+				game.mode = settingsReturn
+			}
+		} else if game.mode == "controls" {
+			if controlsScreen.Update(ui) {
+				settings.Bindings = input.Bindings().Names()
+				err := SaveSettings(settings)
+				if err != nil {
+					fmt.Println("Failed to save settings:", err)
+				}
+				game.mode = controlsReturn
+			}
+		} else if game.mode == "paused" {
+			switch PauseMenu(ui) {
+			case PauseResume:
+				game.Resume()
+			case PauseRestart:
+				game.Resume()
+				if err := game.ResetLevel(); err != nil {
+					game.Fail(err)
+				}
+			case PauseSettings:
+				game.mode = "settings"
+				settingsReturn = "paused"
+			case PauseQuit:
+				game.mode = "menu"
+			}
+		} else if game.mode == "results" {
+			if resultsScreen.Update(ui) >= 0 {
+				if err := game.NextLevel(); err != nil {
+					game.Fail(err)
+				}
+			}
+		} else if game.mode == "gameover" {
+			switch gameOverScreen.Update(ui) {
+			case 0: // Retry
+				game.mode = "game"
+				if err := game.ResetGame(); err != nil {
+					game.Fail(err)
+				}
+			case 1: // Menu
 				game.mode = "menu"
 			}
+		} else if game.mode == "error" {
+			if ErrorScreen(ui, game.err) {
+				game.mode = "menu"
+			}
+		} else if game.mode == "game" {
+			tapped, onButton := gameTouch.Update(touch, touchPos)
+
+			if input.JustPressed(ActionPause) || tapped == pauseButton {
+				game.Pause()
+			}
+
+			// Aim with a dragged finger or with the mouse whenever it moves, otherwise with the keyboard or gamepad
+			if touch.Active && !onButton {
+				game.aimX = touchPos[0]
+			} else if input.MouseMoved() {
+				game.aimX = game.mousePos[0]
+			} else {
+				game.aimX += input.AimAxis() * aimSpeed * frameDt
+			}
 
-			// Limit mouse pos within the level bounds
-			if game.mousePos[0] < game.activeBounds.Min[0] {
-				game.mousePos[0] = game.activeBounds.Min[0]
-			} else if game.mousePos[0] > game.activeBounds.Max[0] {
-				game.mousePos[0] = game.activeBounds.Max[0]
+			// Limit the aim within the level bounds
+			if game.aimX < game.activeBounds.Min[0] {
+				game.aimX = game.activeBounds.Min[0]
+			} else if game.aimX > game.activeBounds.Max[0] {
+				game.aimX = game.activeBounds.Max[0]
 			}
 
+			if input.JustPressed(ActionPreview) {
+				game.showPreview = !game.showPreview
+				settings.ShowPreview = game.showPreview
+				if err := SaveSettings(settings); err != nil {
+					fmt.Println("Failed to save settings:", err)
+				}
+			}
+
+			dt := 128 * time.Millisecond.Seconds()
+			game.previewPath = nil
 			if game.heldShape != nil {
-				game.heldShape.Body().SetPosition(cp.Vector{game.mousePos[0], game.dropHeight})
+				game.heldShape.Body().SetPosition(cp.Vector{game.aimX, game.dropHeight})
 				game.heldShape.Body().SetVelocity(0, 0)
 				game.heldShape.Body().SetAngularVelocity(0)
-				game.heldShape.Body().SetAngle(0)
 
-				if win.JustPressed(glitch.MouseButtonLeft) {
+				if input.JustPressed(ActionRotateLeft) || tapped == rotateButton {
+					game.RotateHeld(math.Pi / 2)
+				}
+				if input.JustPressed(ActionRotateRight) {
+					game.RotateHeld(-math.Pi / 2)
+				}
+				if scroll := input.Scroll(); scroll != 0 {
+					game.RotateHeld(scroll * scrollRotation)
+				}
+				game.heldShape.Body().SetAngle(game.heldAngle)
+
+				if input.JustPressed(ActionHold) || tapped == holdButton {
+					if err := game.SwapHeld(); err != nil {
+						game.Fail(err)
+					}
+				}
+
+				if game.showPreview {
+					game.previewPath = game.PredictDrop(previewSteps(game.difficulty), dt)
+				}
+
+				// Lifting a finger that was aiming drops the package
+				touchDrop := touch.Ended && !onButton
+				if game.heldShape != nil && (input.JustPressed(ActionDrop) || touchDrop) {
 					game.heldShape.Body().SetVelocity(0, -20)
 					game.heldShape = nil
+					game.holdUsed = false
 					game.lastDropTime = time.Now()
 				}
 			}
 
-			game.space.Step(128 * time.Millisecond.Seconds())
+			// Anything above can fail the game, so the level stops here rather than ending underneath the error screen
+			// A hit-stop holds the whole level still for a moment so that heavy impacts land
+			if game.mode == "game" && !game.effects.Frozen() {
+				game.UpdatePegs(dt)
+				stepStart := time.Now()
+				game.space.Step(dt)
+				physicsTime = time.Since(stepStart).Seconds()
+				game.PlayContactEffects()
+				game.UpdateAnimations(frameDt)
+				packingLine.Update(frameDt)
+			}
 			// fixedDt := (16 * time.Millisecond.Seconds()) * math.Ceil(((8 * dt.Seconds()) / (16 * time.Millisecond.Seconds())))
 			// game.space.Step(fixedDt)
 
-			if game.heldShape == nil {
+			if game.mode == "game" && game.heldShape == nil {
 				if time.Since(game.lastDropTime) > 100 * time.Millisecond {
-					game.heldShape = game.GetNextPackage()
+					var err error
+					game.heldShape, err = game.GetNextPackage()
+					if err != nil {
+						game.Fail(err)
+					}
 				}
 			}
 
-			if len(game.packages) <= 0 && game.heldShape == nil {
+			if game.mode == "game" && len(game.packages) <= 0 && game.heldShape == nil {
 				stillActive := false
 				game.space.EachBody(func(body *cp.Body) {
+					// Kinematic pegs never go idle, so only wait on things that can actually settle
+					if body.GetType() != cp.BODY_DYNAMIC { return }
 					// fmt.Println("Idle", body.IdleTime())
 					// Don't search if something is still active
-					if body.IdleTime() < 0.1 {
+					if body.IdleTime() < settledIdleTime {
 						stillActive = true
 					}
 				})
@@ -176,59 +574,51 @@ func run() {
 					timeoutEndLevel = true
 				}
 
-				if game.idleCounter > 100 || timeoutEndLevel {
-					// Check end conditions
-					areaBB := cp.BB{
-						L: game.acceptBounds.Min[0],
-						B: game.acceptBounds.Min[1],
-						R: game.acceptBounds.Max[0],
-						T: game.acceptBounds.Max[1],
-					}
-
-					healthLost := 0
-					game.space.EachShape(func(shape *cp.Shape) {
-						sprite := shape.Body().UserData.(Sprite)
-						if !sprite.isPackage { return } // Skip if not a package
-
-						if !areaBB.Contains(shape.BB()) {
-							healthLost++
-						}
-					})
+				if game.idleCounter > settledFrames || timeoutEndLevel {
+					game.EndLevel()
+					resultsScreen.SetLevelResult(game.lastResult, game.score)
+					gameOverScreen.SetGameOver(game.lastResult, game.score, game.newRecord)
+				}
+			}
+		}
 
-					game.health -= healthLost
-					if game.health <= 0 {
-						if game.difficulty > game.record {
-							game.record = game.difficulty
-						}
-						game.mode = "menu"
-					}
+		// Particles keep going on the end of level screens so that the confetti and puffs play out
+		if game.mode == "game" || game.mode == "results" || game.mode == "gameover" {
+			game.particles.Update(frameDt)
+		}
 
-					game.difficulty++
+		// Zoom in on the bins while the level's results are up
+		if game.mode == "results" || game.mode == "gameover" {
+			game.effects.ZoomTo(game.acceptBounds, screen)
+		} else {
+			game.effects.ResetZoom()
+		}
+		game.effects.Update(frameDt)
 
-					game.ResetLevel()
-				}
+		if toggleMute {
+			settings.Muted = !settings.Muted
+			if game.player != nil {
+				game.player.SetMuted(settings.Muted)
+			}
+			if err := SaveSettings(settings); err != nil {
+				fmt.Println("Failed to save settings:", err)
 			}
 		}
 
 		pass.Clear()
+		hudPass.Clear()
 
-		if game.mode == "menu" {
-			rect := glitch.R(-300, 0, 300, 100)
-			menuText.DrawRect(pass, rect, glitch.White)
-			muteText.DrawRect(pass,
-				glitch.R(-win.Bounds().W()/2, -win.Bounds().H()/2, -win.Bounds().W()/2 + 300, win.Bounds().H()/2 + 300),
-				glitch.White)
+		// The menus are drawn entirely by the UI, but the overlays that are opened from inside a level draw over it
+		inLevel := game.mode != "menu" && game.mode != "controls" && game.mode != "error" && !(game.mode == "settings" && settingsReturn == "menu")
 
-			{
-				theta := float64(time.Now().UnixMilli()) / 1000
-				textOscillation := 5 * math.Sin(7 * theta)
-				recordText.Set(fmt.Sprintf("High Score: %d", game.record))
-				recordText.DrawRect(pass,
-					rect.Moved(glitch.Vec2{130, -200 + textOscillation}),
-					glitch.FromUint8(0xfa, 0xcb, 0x3e, 0xff))
+		if inLevel {
+			background.RectDraw(pass, game.levelBounds)
+			if frame := packingLine.Frame(); frame.sprite != nil {
+				frame.sprite.RectDraw(pass, game.levelBounds)
+			}
+			if err := game.DrawBins(pass); err != nil {
+				game.Fail(err)
 			}
-		} else if game.mode == "game" {
-			packingLine.RectDraw(pass, game.levelBounds)
 			// {
 			// 	mat := glitch.Mat4Ident
 			// 	mat.Scale(4, 4, 1)
@@ -238,54 +628,123 @@ func run() {
 			game.space.EachBody(func(body *cp.Body) {
 				DrawBody(pass, body)
 			})
+			game.particles.Draw(pass)
+			physicsDebug.Draw(pass, hudPass, game, screen)
+
+			if game.showPreview {
+				game.DrawPreview(pass, game.previewPath)
+			}
+
+			if err := game.DrawNextPackages(pass, 8); err != nil {
+				game.Fail(err)
+			}
+			if err := game.DrawHoldPackage(pass); err != nil {
+				game.Fail(err)
+			}
+			holdText.DrawRect(pass, glitch.R(game.levelBounds.Min[0] - queueOffset - 100, game.dropHeight, game.levelBounds.Min[0] - queueOffset + 100, game.dropHeight + 100), glitch.White)
 
-			game.DrawNextPackages(pass, 8)
+			if input.UsingTouch() && game.mode == "game" {
+				rotateButton.Draw(hudPass, buttonPanel)
+				holdButton.Draw(hudPass, buttonPanel)
+				pauseButton.Draw(hudPass, buttonPanel)
+			}
 
 			{
 				healthText.Set(fmt.Sprintf(" Health: %d", game.health))
 				mat := glitch.Mat4Ident
-				mat.Translate(-win.Bounds().W()/2, -win.Bounds().H()/2, 0)
-				healthText.Draw(pass, mat)
+				mat.Translate(screen.Min[0], screen.Min[1], 0)
+				healthText.Draw(hudPass, mat)
 			}
 		}
 
+		perfHUD.Draw(hudPass, screen)
+
 		// glitch.Clear(win, glitch.Black)
 		// glitch.Clear(win, glitch.FromUint8(0x48, 0x3b, 0x3a, 0xff))
 		glitch.Clear(win, glitch.FromUint8(0x6b, 0x6b, 0x6b, 0xff))
 
-		pass.SetUniform("projection", camera.Projection)
-		pass.SetUniform("view", camera.View)
+		worldCamera := camera
+		if inLevel {
+			worldCamera = game.effects.Camera(viewport)
+		}
+		pass.SetUniform("projection", worldCamera.Projection)
+		pass.SetUniform("view", worldCamera.View)
 		pass.Draw(win)
+		hudPass.SetUniform("projection", camera.Projection)
+		hudPass.SetUniform("view", camera.View)
+		hudPass.Draw(win)
+		ui.Draw(win, camera)
+		viewport.DrawLetterbox(win)
 
 		win.Update()
 
-		// dt = time.Since(frameStart)
-		// frameStart = time.Now()
+		stats := FrameStats{
+			frameTime: time.Since(frameStart).Seconds(),
+			physicsTime: physicsTime,
+			drawCalls: pass.Stats().DrawCalls + hudPass.Stats().DrawCalls + ui.DrawCalls() + viewport.DrawCalls(),
+		}
+		if game.space != nil {
+			game.space.EachBody(func(body *cp.Body) {
+				stats.bodies++
+				body.EachShape(func(*cp.Shape) { stats.shapes++ })
+			})
+		}
+		perfHUD.Record(stats)
+		profiler.Record(stats)
+
 	}
+	return nil
 }
 
 type Game struct {
 	mode string
-	record int
+	err error // The error being shown on the error screen
+	record int // The best score so far
+	score int
+	newRecord bool // Set when the last game beat the previous record
+	lastResult LevelResult
 
 	win *glitch.Window
 	spritesheet *asset.Spritesheet
 	space *cp.Space
 	difficulty int
+	startDifficulty int // The level that new games start on
+	levels map[int]Level // Hand authored level data, indexed by difficulty
+	animations map[string]aseprite.Animation
+	pegAnimations map[PegKind]*SpriteAnimation
+	packageAnimation *SpriteAnimation
+	packingLineAnimation *SpriteAnimation
 
 	mousePos glitch.Vec3
+	aimX float64 // Where the held package is lined up to drop from
 
 	dropHeight float64
 	levelBounds, activeBounds, pegBounds, acceptBounds glitch.Rect
+	bins []Bin
 
 	health int
 	idleCounter int
 
 	heldShape *cp.Shape
+	heldPackage string // The sprite name of the held package
+	heldAngle float64
+	holdPackage string // The package stored in the hold slot, if any
+	holdUsed bool // Set once the hold slot has been used for the current drop
 	packages []string
 
 	lastDropTime time.Time
+	pausedAt time.Time
+	showPreview bool // Draws the predicted path of the held package
+	previewPath []glitch.Vec3
+	preview previewSpace
+	previewMesh *glitch.Mesh
+	particles *ParticleSystem
+	effects *CameraEffects
+	levelTime float64 // Simulated seconds since the level started
 	allPegs []phy2.Pos
+	pegs []*Peg
+	pegSpacing float64 // The minimum distance between peg centers
+	pegLayout string // The name of the peg layout used by the current level
 
 	// Audio
 	player *AudioPlayer
@@ -298,6 +757,11 @@ func NewGame(win *glitch.Window, levelBounds glitch.Rect, spritesheet *asset.Spr
 		spritesheet: spritesheet,
 		health: 10,
 		difficulty: 0,
+		pegSpacing: 8 * 16.0,
+		showPreview: true,
+		previewMesh: glitch.NewMesh(),
+		particles: NewParticleSystem(),
+		effects: NewCameraEffects(),
 
 		levelBounds: levelBounds,
 	}
@@ -305,13 +769,16 @@ func NewGame(win *glitch.Window, levelBounds glitch.Rect, spritesheet *asset.Spr
 	return game
 }
 
-func (g *Game) ResetGame() {
+func (g *Game) ResetGame() error {
 	g.health = 10
-	g.difficulty = 0
-	g.ResetLevel()
+	g.difficulty = g.startDifficulty
+	g.score = 0
+	g.newRecord = false
+	return g.ResetLevel()
 }
 
-func (g *Game) ResetLevel() {
+func (g *Game) ResetLevel() error {
+	g.particles.Clear()
 	g.space = cp.NewSpace()
 	g.space.Iterations = 16
 	// g.space.IdleSpeedThreshold = 0.1
@@ -319,6 +786,7 @@ func (g *Game) ResetLevel() {
 
 	// g.space.UseSpatialHash(2.0, 10)
 	g.space.SetGravity(cp.Vector{0, Gravity})
+	g.AddPegCollisionHandlers()
 
 	// handler := g.space.NewCollisionHandler(cp.WILDCARD_COLLISION_TYPE, cp.WILDCARD_COLLISION_TYPE)
 	// handler.BeginFunc = func(arb *cp.Arbiter, space *cp.Space, userData interface{}) bool {
@@ -345,8 +813,10 @@ func (g *Game) ResetLevel() {
 		}
 
 		for _, wall := range walls {
-			ninePanel, err := g.spritesheet.GetNinePanel("wall-0.png", glitch.R(8, 8, 8, 8))
-			if err != nil { panic(err) }
+			ninePanel, err := g.WallPanel()
+			if err != nil {
+				return err
+			}
 
 			s := NewSprite(nil)
 			s.ninePanel = ninePanel
@@ -359,20 +829,13 @@ func (g *Game) ResetLevel() {
 		}
 	}
 
-	packageTable := NewRngTable(
-		NewRngItem(20, "package-0.png"),
-		NewRngItem(20, "package-1.png"),
-		NewRngItem(20, "package-2.png"),
-		NewRngItem(20, "package-3.png"),
-		NewRngItem(20, "package-4.png"),
-		NewRngItem(20, "package-5.png"),
-		NewRngItem(20, "package-6.png"),
-		NewRngItem(10, "package-7.png"),
-		NewRngItem(1, "package-8.png"),
-		NewRngItem(1, "package-9.png"),
-		NewRngItem(1, "package-10.png"),
-		NewRngItem(1, "package-11.png"),
-	)
+	level := g.levels[g.difficulty]
+	for _, conveyor := range level.Conveyors {
+		err := g.AddConveyor(conveyor)
+		if err != nil {
+			return err
+		}
+	}
 
 	// numSprites := 11
 	g.packages = make([]string, 10 + g.difficulty)
@@ -384,94 +847,156 @@ func (g *Game) ResetLevel() {
 	g.activeBounds = g.levelBounds.Unpad(glitch.R(100, 0, 100, 0))
 	g.pegBounds = g.activeBounds.Unpad(glitch.R(0, g.levelBounds.H()/2, 0, 100))
 	g.acceptBounds = g.levelBounds.Unpad(glitch.R(0, 0, 0, 100 + g.levelBounds.H()/2))
+	err := g.AddBins(level.Bins)
+	if err != nil {
+		return err
+	}
 
-	g.heldShape = g.GetNextPackage()
+	// The first package spawns at the drop height, so it has to be set for this level first
+	g.dropHeight = g.levelBounds.Max[1] + 200
+	g.holdPackage = ""
+	g.holdUsed = false
+	g.heldShape, err = g.GetNextPackage()
+	if err != nil {
+		return err
+	}
 
 	numPegs := 10 + g.difficulty
 	g.allPegs = make([]phy2.Pos, 0)
-	for i := 0; i < numPegs; i++ {
-		g.AddPeg()
+	g.pegs = make([]*Peg, 0)
+	g.levelTime = 0
+	g.pegLayout = level.Layout
+	if g.pegLayout == "" {
+		g.pegLayout = LayoutForLevel(g.difficulty)
+	}
+	err = g.PlacePegs(numPegs)
+	if err != nil {
+		return err
 	}
 
-	g.dropHeight = g.levelBounds.Max[1] + 200
 	g.idleCounter = 0
+	return nil
 }
 
-func (g *Game) AddPeg() {
-	minDistance := 8 * 16.0
-
-	attempts := 0
-
-	var x, y float64
-	for {
-		attempts++
-		if attempts > 10 {
-			return
-		}
-
-		tooClose := false
-		x = (rand.Float64() * g.pegBounds.W()) + g.pegBounds.Min[0]
-		y = (rand.Float64() * g.pegBounds.H()) + g.pegBounds.Min[1]
-
-		for i := range g.allPegs {
-			if g.allPegs[i].Sub(phy2.Pos{x, y}).Len() < minDistance {
-				tooClose = true
-			}
-		}
-
-		if tooClose {
-			continue
-		} else {
-			break
-		}
+func (g *Game) AddPeg(kind PegKind, x, y float64) error {
+	pegAnimation, ok := g.pegAnimations[kind]
+	if !ok {
+		return fmt.Errorf("no animation for peg kind %d", kind)
+	}
+	anim := NewAnimatedSprite(pegAnimation, "idle")
+	sprite := anim.Frame().sprite
+	if sprite == nil {
+		return fmt.Errorf("peg kind %d has no sprite", kind)
 	}
-
 	g.allPegs = append(g.allPegs, phy2.Pos{x, y})
 
-	sprite, err := g.spritesheet.Get("peg-0.png")
-	if err != nil { panic(err) }
 	s := NewSprite(sprite)
+	s.anim = anim
+
+	peg := &Peg{
+		kind: kind,
+		origin: cp.Vector{x, y},
+		extent: g.pegSpacing / 3,
+		phase: rand.Float64() * 2 * math.Pi,
+		hitsLeft: breakableHits,
+	}
+	s.peg = peg
+
+	var shape *cp.Shape
+	switch kind {
+	case PegBumper:
+		shape = makeBumper(s, x, y)
+	case PegLinear, PegOrbit:
+		shape = makeMovingPeg(s, x, y)
+		shape.Body().SetPosition(peg.PathPosition(0))
+	case PegBar:
+		length := 0.8 * g.pegSpacing
+		s.scale = glitch.Vec2{length / sprite.Bounds().W(), barThickness / sprite.Bounds().H()}
+		shape = makeBar(s, x, y, length)
+		shape.Body().SetAngle(peg.phase)
+	case PegBreakable:
+		shape = makePeg(s, x, y)
+		setCollisionType(shape, CollisionBreakable)
+	default:
+		shape = makePeg(s, x, y)
+	}
+
+	peg.body = shape.Body()
+	peg.shape = shape
+	g.pegs = append(g.pegs, peg)
 
-	shape := makePeg(s, x, y)
 	g.space.AddBody(shape.Body())
 	g.space.AddShape(shape)
+	return nil
 }
 
-func (g *Game) GetNextPackage() *cp.Shape {
+// Spawns the next package on the line. Returns a nil shape once there are none left
+func (g *Game) GetNextPackage() (*cp.Shape, error) {
 	if len(g.packages) <= 0 {
-		return nil
+		// Once the line is empty, whatever is in the hold slot has to go out too
+		if g.holdPackage != "" {
+			pkg := g.holdPackage
+			g.holdPackage = ""
+			return g.SpawnPackage(pkg)
+		}
+		return nil, nil
 	}
 
 	pkg := g.packages[0]
 	g.packages = g.packages[1:]
 	// fmt.Println("NextPackage: ", pkg)
+	return g.SpawnPackage(pkg)
+}
+
+// Loads the font into an atlas holding the printable ASCII characters
+func LoadAtlas(load *asset.Load, filepath string) (*glitch.Atlas, error) {
+	font, err := load.Font(filepath, 64)
+	if err != nil {
+		return nil, err
+	}
+	runes := make([]rune, unicode.MaxASCII - 32)
+	for i := range runes {
+		runes[i] = rune(32 + i)
+	}
+	return glitch.NewAtlas(font, runes, true, 0), nil
+}
+
+// Creates the held package for the sprite name and adds it to the space
+func (g *Game) SpawnPackage(pkg string) (*cp.Shape, error) {
 	sprite, err := g.spritesheet.Get(pkg)
-	if err != nil { panic(err) }
+	if err != nil {
+		return nil, err
+	}
 	s := NewSprite(sprite)
+	s.category = packageCategories[pkg]
+	s.anim = NewAnimatedSprite(g.packageAnimation, "idle")
 
 	shape := makePackage(s, 0, 250)
-	shape.Body().SetPosition(cp.Vector{g.mousePos[0], g.dropHeight})
+	shape.Body().SetPosition(cp.Vector{g.aimX, g.dropHeight})
 
 	g.space.AddBody(shape.Body())
 	g.space.AddShape(shape)
 
-	return shape
-}
+	g.heldPackage = pkg
+	g.heldAngle = 0
 
-func (g *Game) DrawNextPackages(pass *glitch.RenderPass, num int) {
-	screenHeight := g.win.Bounds().H()
+	return shape, nil
+}
 
-	packageOffset :=  (3.0/4.0) * screenHeight / float64(num)
+func (g *Game) DrawNextPackages(pass *glitch.RenderPass, num int) error {
+	// The first slot is the hold slot
+	packageOffset :=  (3.0/4.0) * virtualHeight / float64(num + 1)
 
-	startY := g.dropHeight - 100
-	startX := g.levelBounds.Min[0] - 300
+	startY := g.dropHeight - 100 - packageOffset
+	startX := g.levelBounds.Min[0] - queueOffset
 
 	for i := 0; i < num; i++ {
 		if i >= len(g.packages) { break }
 
 		sprite, err := g.spritesheet.Get(g.packages[i])
-		if err != nil { panic(err) }
-
+		if err != nil {
+			return err
+		}
 
 		mat := glitch.Mat4Ident
 		mat.Translate(startX, startY, 0)
@@ -479,26 +1004,34 @@ func (g *Game) DrawNextPackages(pass *glitch.RenderPass, num int) {
 
 		startY -= packageOffset
 	}
+	return nil
 }
 
 func DrawBody(pass *glitch.RenderPass, body *cp.Body) {
-	geom := glitch.NewGeomDraw()
-	geom.SetColor(glitch.White)
-
 	sprite := body.UserData.(Sprite)
 
 	pos := body.Position()
 	angle := body.Angle()
 
+	img := sprite.sprite
+	scale := sprite.scale
+	color := sprite.color
+	if sprite.anim != nil {
+		frame := sprite.anim.Frame()
+		if frame.sprite != nil {
+			img = frame.sprite
+		}
+		scale = glitch.Vec2{scale[0] * (1 + frame.stretch[0]), scale[1] * (1 + frame.stretch[1])}
+	}
 
-	if sprite.sprite != nil {
+	if img != nil {
 		mat := glitch.Mat4Ident
-		mat.Scale(sprite.scale[0], sprite.scale[1], 1.0)
+		mat.Scale(scale[0], scale[1], 1.0)
 		mat.Rotate(angle, glitch.Vec3{0, 0, 1})
 		mat.Translate(pos.X, pos.Y, 0)
-		sprite.sprite.Draw(pass, mat)
+		img.DrawColorMask(pass, mat, color)
 	} else if sprite.ninePanel != nil {
-		sprite.ninePanel.RectDraw(pass, sprite.rect.Moved(glitch.Vec2{pos.X, pos.Y}))
+		sprite.ninePanel.RectDrawColorMask(pass, sprite.rect.Moved(glitch.Vec2{pos.X, pos.Y}), color)
 	}
 }
 
@@ -514,7 +1047,7 @@ func makePackage(sprite Sprite, x, y float64) *cp.Shape {
 
 	// shape := cp.NewCircle(body, 8, cp.Vector{})
 	shape := cp.NewBox(body, width, height, 0)
-	shape.SetCollisionType(cp.WILDCARD_COLLISION_TYPE)
+	setCollisionType(shape, CollisionPackage)
 	shape.SetElasticity(0.5)
 	shape.SetDensity(1)
 	shape.SetFriction(0.5)
@@ -532,7 +1065,7 @@ func makePeg(sprite Sprite, x, y float64) *cp.Shape {
 	body.UserData = sprite
 
 	shape := cp.NewCircle(body, radius, cp.Vector{})
-	shape.SetCollisionType(cp.WILDCARD_COLLISION_TYPE)
+	setCollisionType(shape, CollisionPeg)
 	shape.SetElasticity(0.5)
 	shape.SetDensity(1)
 	shape.SetFriction(0.2)
@@ -565,11 +1098,16 @@ type Sprite struct {
 	ninePanel *glitch.NinePanelSprite
 	rect glitch.Rect
 	scale glitch.Vec2
+	color glitch.RGBA
 	isPackage bool
+	category string // The sorting category of a package
+	peg *Peg // Set if this body is a peg
+	anim *AnimatedSprite // Optional, applied on top of the rest of the sprite when drawing
 }
 func NewSprite(s *glitch.Sprite) Sprite {
 	return Sprite{
 		sprite: s,
 		scale: glitch.Vec2{1, 1},
+		color: glitch.White,
 	}
 }
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/unitoftime/glitch"
+)
+
+// The main menu options
+const (
+	MenuNone = iota - 1
+	MenuPlay
+	MenuSettings
+	MenuControls
+	MenuMute
+)
+
+// Declares the main menu. Returns the option that was picked this frame, or MenuNone
+func MainMenu(ui *UI, bindings Bindings, record int) int {
+	width := 500.0
+	height := 100.0
+	gap := 25.0
+
+	picked := MenuNone
+	column := NewColumn(Anchored(ui.Screen(), AnchorCenter, width, ColumnHeight(3, height, gap)), gap)
+	if ui.Button("Play", column.Next(height)) {
+		picked = MenuPlay
+	}
+	if ui.Button("Settings", column.Next(height)) {
+		picked = MenuSettings
+	}
+	if ui.Button("Controls", column.Next(height)) {
+		picked = MenuControls
+	}
+
+	theta := float64(time.Now().UnixMilli()) / 1000
+	textOscillation := 5 * math.Sin(7 * theta)
+	recordRect := column.Next(height).Moved(glitch.Vec2{0, textOscillation})
+	ui.Label(fmt.Sprintf("High Score: %d", record), recordRect, AnchorCenter, 1, highlightColor)
+
+	corner := Anchored(ui.Screen().Unpad(glitch.R(50, 50, 50, 50)), AnchorBottomLeft, 300, 150)
+	if ui.input.UsingTouch() {
+		if ui.Button("Mute", corner) {
+			picked = MenuMute
+		}
+	} else {
+		hints := Anchored(ui.Screen().Unpad(glitch.R(25, 25, 25, 25)), AnchorBottomLeft, 800, 160)
+		ui.Label(fmt.Sprintf("Press %s For Controls", bindings.KeyName(ActionControls)), hints, AnchorTopLeft, 1, glitch.White)
+		ui.Label(fmt.Sprintf("Press %s To Mute", bindings.KeyName(ActionMute)), hints, AnchorBottomLeft, 1, glitch.White)
+	}
+
+	return picked
+}
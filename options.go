@@ -0,0 +1,52 @@
+package main
+
+import (
+	"github.com/unitoftime/glitch"
+)
+
+// The buttons at the bottom of the settings screen
+const (
+	SettingsNone = iota - 1
+	SettingsControls
+	SettingsBack
+)
+
+// Declares the settings screen, which edits settings in place. Returns the button that was picked this frame, or SettingsNone, and whether any setting changed
+func SettingsMenu(ui *UI, settings *Settings) (int, bool) {
+	width := 800.0
+	height := 80.0
+	gap := 15.0
+	area := Anchored(ui.Screen(), AnchorCenter, width, 150 + ColumnHeight(8, height, gap))
+	column := NewColumn(area, gap)
+
+	ui.Label("Settings", column.Next(125), AnchorCenter, 1, glitch.White)
+
+	changed := false
+	if ui.Slider("Volume", &settings.Volume, 0, 1, column.Next(height)) {
+		changed = true
+	}
+	if ui.Toggle("Mute Music", &settings.Muted, column.Next(height)) {
+		changed = true
+	}
+	if ui.Toggle("Drop Preview", &settings.ShowPreview, column.Next(height)) {
+		changed = true
+	}
+	if ui.Slider("Screen Shake", &settings.ScreenShake, 0, 1, column.Next(height)) {
+		changed = true
+	}
+	if ui.Toggle("Hit Stop", &settings.HitStop, column.Next(height)) {
+		changed = true
+	}
+	if ui.Toggle("Level End Zoom", &settings.CameraZoom, column.Next(height)) {
+		changed = true
+	}
+
+	picked := SettingsNone
+	if ui.Button("Controls", column.Next(height)) {
+		picked = SettingsControls
+	}
+	if ui.Button("Back", column.Next(height)) || ui.win.JustPressed(glitch.KeyEscape) || ui.input.JustPressedButton(GamepadB) {
+		picked = SettingsBack
+	}
+	return picked, changed
+}
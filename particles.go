@@ -0,0 +1,237 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/unitoftime/glitch"
+)
+
+// The most particles that can be alive at once. Emitting past this reuses the oldest particles
+const maxParticles = 1024
+
+type Particle struct {
+	pos, vel glitch.Vec2
+	age, lifetime float64 // In seconds
+	size, endSize float64
+	angle, spin float64
+	color, endColor glitch.RGBA
+	gravity float64
+	drag float64 // The fraction of speed lost each second
+}
+
+// Describes a burst of particles. Each particle picks its own values between the min and max of each range
+type ParticleEffect struct {
+	count int
+	spread float64 // How far either side of the emit direction particles can go, in radians
+	speed [2]float64
+	lifetime [2]float64
+	size [2]float64
+	endScale float64 // The size at the end of a particle's life, relative to its starting size
+	spin float64 // The fastest that particles can spin either way, in radians per second
+	colors []glitch.RGBA // Each particle picks one of these at random
+	fade bool // Fades particles out over their lifetime
+	gravity float64
+	drag float64
+	offset float64 // How far from the emit point particles can start, in any direction
+}
+
+var (
+	// Kicked up when a package lands on something
+	dustEffect = ParticleEffect{
+		count: 8,
+		spread: math.Pi / 2,
+		speed: [2]float64{40, 120},
+		lifetime: [2]float64{0.3, 0.6},
+		size: [2]float64{6, 12},
+		endScale: 2,
+		colors: []glitch.RGBA{
+			glitch.FromUint8(0xb8, 0xa6, 0x8e, 0xff),
+			glitch.FromUint8(0x9c, 0x8a, 0x74, 0xff),
+		},
+		fade: true,
+		drag: 3,
+		offset: 6,
+	}
+
+	// Thrown off of a peg when a package hits it
+	sparkEffect = ParticleEffect{
+		count: 6,
+		spread: math.Pi / 3,
+		speed: [2]float64{200, 400},
+		lifetime: [2]float64{0.15, 0.3},
+		size: [2]float64{3, 5},
+		endScale: 0.25,
+		colors: []glitch.RGBA{
+			glitch.FromUint8(0xff, 0xf2, 0xa8, 0xff),
+			glitch.FromUint8(0xfa, 0xcb, 0x3e, 0xff),
+		},
+		gravity: -600,
+		drag: 2,
+	}
+
+	// Rains down over the level when it is cleared
+	confettiEffect = ParticleEffect{
+		count: 150,
+		spread: math.Pi / 4,
+		speed: [2]float64{50, 300},
+		lifetime: [2]float64{2, 3.5},
+		size: [2]float64{8, 14},
+		endScale: 1,
+		spin: 8,
+		colors: []glitch.RGBA{
+			glitch.FromUint8(0xd9, 0x57, 0x63, 0xff),
+			glitch.FromUint8(0xfa, 0xcb, 0x3e, 0xff),
+			glitch.FromUint8(0x5f, 0xcd, 0xe4, 0xff),
+			glitch.FromUint8(0x99, 0xe5, 0x50, 0xff),
+			glitch.FromUint8(0xcb, 0xdb, 0xfc, 0xff),
+		},
+		gravity: -300,
+		drag: 1,
+		offset: 400,
+	}
+
+	// Marks each package that ended up outside of the bins
+	lostEffect = ParticleEffect{
+		count: 20,
+		spread: math.Pi,
+		speed: [2]float64{60, 200},
+		lifetime: [2]float64{0.5, 0.9},
+		size: [2]float64{10, 20},
+		endScale: 2.5,
+		colors: []glitch.RGBA{
+			glitch.FromUint8(0xd9, 0x57, 0x63, 0xff),
+			glitch.FromUint8(0xac, 0x32, 0x32, 0xff),
+		},
+		fade: true,
+		drag: 2.5,
+		offset: 20,
+	}
+)
+
+// A fixed pool of particles that are all drawn as colored squares
+type ParticleSystem struct {
+	particles []Particle // The live particles are packed at the front
+	count int
+	next int // The particle that gets reused when the pool is full
+	mesh *glitch.Mesh // A unit square centered on the origin
+}
+
+func NewParticleSystem() *ParticleSystem {
+	geom := glitch.NewGeomDraw()
+	geom.SetColor(glitch.White)
+	return &ParticleSystem{
+		particles: make([]Particle, maxParticles),
+		mesh: geom.FillRect(glitch.R(-0.5, -0.5, 0.5, 0.5)),
+	}
+}
+
+// Starts a burst of the effect at pos, heading in the direction of angle
+func (s *ParticleSystem) Emit(effect ParticleEffect, pos glitch.Vec2, angle float64) {
+	for i := 0; i < effect.count; i++ {
+		theta := angle + (2 * rand.Float64() - 1) * effect.spread
+		speed := randRange(effect.speed)
+		size := randRange(effect.size)
+
+		color := glitch.White
+		if len(effect.colors) > 0 {
+			color = effect.colors[rand.Intn(len(effect.colors))]
+		}
+		endColor := color
+		if effect.fade {
+			endColor = glitch.RGBA{}
+		}
+
+		start := pos
+		if effect.offset > 0 {
+			start = start.Add(glitch.Vec2{
+				(2 * rand.Float64() - 1) * effect.offset,
+				(2 * rand.Float64() - 1) * effect.offset,
+			})
+		}
+
+		s.add(Particle{
+			pos: start,
+			vel: glitch.Vec2{speed * math.Cos(theta), speed * math.Sin(theta)},
+			lifetime: randRange(effect.lifetime),
+			size: size,
+			endSize: size * effect.endScale,
+			angle: rand.Float64() * 2 * math.Pi,
+			spin: (2 * rand.Float64() - 1) * effect.spin,
+			color: color,
+			endColor: endColor,
+			gravity: effect.gravity,
+			drag: effect.drag,
+		})
+	}
+}
+
+func (s *ParticleSystem) add(p Particle) {
+	if s.count < len(s.particles) {
+		s.particles[s.count] = p
+		s.count++
+		return
+	}
+	s.particles[s.next] = p
+	s.next = (s.next + 1) % len(s.particles)
+}
+
+// Removes every particle, such as when the level changes
+func (s *ParticleSystem) Clear() {
+	s.count = 0
+	s.next = 0
+}
+
+func (s *ParticleSystem) Count() int {
+	return s.count
+}
+
+// Moves the particles forward by dt seconds and retires the ones that have run out of life
+func (s *ParticleSystem) Update(dt float64) {
+	for i := 0; i < s.count; {
+		p := &s.particles[i]
+		p.age += dt
+		if p.age >= p.lifetime {
+			// Swap remove so that the live particles stay packed
+			s.count--
+			s.particles[i] = s.particles[s.count]
+			continue
+		}
+
+		p.vel[1] += p.gravity * dt
+		p.vel = p.vel.Scaled(math.Max(0, 1 - p.drag * dt))
+		p.pos = p.pos.Add(p.vel.Scaled(dt))
+		p.angle += p.spin * dt
+		i++
+	}
+	if s.next >= s.count {
+		s.next = 0
+	}
+}
+
+func (s *ParticleSystem) Draw(pass *glitch.RenderPass) {
+	for i := 0; i < s.count; i++ {
+		p := s.particles[i]
+		t := p.age / p.lifetime
+		size := p.size + (p.endSize - p.size) * t
+
+		mat := glitch.Mat4Ident
+		mat.Scale(size, size, 1)
+		mat.Rotate(p.angle, glitch.Vec3{0, 0, 1})
+		mat.Translate(p.pos[0], p.pos[1], 0)
+		s.mesh.DrawColorMask(pass, mat, lerpColor(p.color, p.endColor, t))
+	}
+}
+
+func randRange(r [2]float64) float64 {
+	return r[0] + rand.Float64() * (r[1] - r[0])
+}
+
+func lerpColor(a, b glitch.RGBA, t float64) glitch.RGBA {
+	return glitch.RGBA{
+		R: a.R + (b.R - a.R) * t,
+		G: a.G + (b.G - a.G) * t,
+		B: a.B + (b.B - a.B) * t,
+		A: a.A + (b.A - a.A) * t,
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"time"
+
+	"github.com/unitoftime/glitch"
+)
+
+// The pause options, in the order their buttons are listed
+const (
+	PauseNone = iota - 1
+	PauseResume
+	PauseRestart
+	PauseSettings
+	PauseQuit
+)
+
+var pauseOptions = []string{
+	PauseResume: "Resume",
+	PauseRestart: "Restart Level",
+	PauseSettings: "Settings",
+	PauseQuit: "Quit To Menu",
+}
+
+// Declares the pause overlay. Returns the option that was picked this frame, or PauseNone
+func PauseMenu(ui *UI) int {
+	if ui.input.JustPressed(ActionPause) {
+		return PauseResume
+	}
+
+	width := 500.0
+	height := 100.0
+	gap := 25.0
+	area := Anchored(ui.Screen(), AnchorCenter, width, 150 + ColumnHeight(len(pauseOptions), height, gap))
+	column := NewColumn(area, gap)
+
+	ui.Label("Paused", column.Next(125), AnchorCenter, 1, glitch.White)
+
+	picked := PauseNone
+	for i, option := range pauseOptions {
+		if ui.Button(option, column.Next(height)) {
+			picked = i
+		}
+	}
+	return picked
+}
+
+// Freezes the drop timers so that time spent paused doesn't count towards them
+func (g *Game) Pause() {
+	g.mode = "paused"
+	g.pausedAt = time.Now()
+}
+
+func (g *Game) Resume() {
+	g.mode = "game"
+	g.lastDropTime = g.lastDropTime.Add(time.Since(g.pausedAt))
+}
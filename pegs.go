@@ -0,0 +1,116 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/unitoftime/glitch"
+)
+
+// The number of candidates tried around each active sample before it is retired. 30 is the value suggested in Bridson's paper
+const poissonAttempts = 30
+
+// Generates points inside of bounds where no two points are closer than minDistance, using Bridson's Poisson-disc algorithm
+// See: https://www.cs.ubc.ca/~rbridson/docs/bridson-siggraph07-poissondisk.pdf
+func PoissonDisc(bounds glitch.Rect, minDistance float64) []glitch.Vec2 {
+	if minDistance <= 0 || bounds.W() <= 0 || bounds.H() <= 0 {
+		return nil
+	}
+
+	// Each grid cell can hold at most one point because the cell diagonal is minDistance
+	cellSize := minDistance / math.Sqrt2
+	cols := int(math.Ceil(bounds.W() / cellSize))
+	rows := int(math.Ceil(bounds.H() / cellSize))
+	grid := make([]int, cols * rows)
+	for i := range grid {
+		grid[i] = -1
+	}
+
+	cellOf := func(p glitch.Vec2) (int, int) {
+		x := int((p[0] - bounds.Min[0]) / cellSize)
+		y := int((p[1] - bounds.Min[1]) / cellSize)
+		if x >= cols { x = cols - 1 }
+		if y >= rows { y = rows - 1 }
+		return x, y
+	}
+
+	points := make([]glitch.Vec2, 0)
+	active := make([]int, 0)
+
+	add := func(p glitch.Vec2) {
+		x, y := cellOf(p)
+		grid[y * cols + x] = len(points)
+		active = append(active, len(points))
+		points = append(points, p)
+	}
+
+	tooClose := func(p glitch.Vec2) bool {
+		x, y := cellOf(p)
+		for j := y - 2; j <= y + 2; j++ {
+			if j < 0 || j >= rows { continue }
+			for i := x - 2; i <= x + 2; i++ {
+				if i < 0 || i >= cols { continue }
+				idx := grid[j * cols + i]
+				if idx < 0 { continue }
+				if p.Sub(points[idx]).Len() < minDistance {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	add(glitch.Vec2{
+		bounds.Min[0] + rand.Float64() * bounds.W(),
+		bounds.Min[1] + rand.Float64() * bounds.H(),
+	})
+
+	for len(active) > 0 {
+		a := rand.Intn(len(active))
+		origin := points[active[a]]
+
+		found := false
+		for k := 0; k < poissonAttempts; k++ {
+			// Pick a point in the annulus between minDistance and 2 * minDistance
+			theta := rand.Float64() * 2 * math.Pi
+			radius := minDistance * (1 + rand.Float64())
+			p := glitch.Vec2{
+				origin[0] + radius * math.Cos(theta),
+				origin[1] + radius * math.Sin(theta),
+			}
+
+			if !bounds.Contains(p[0], p[1]) { continue }
+			if tooClose(p) { continue }
+
+			add(p)
+			found = true
+			break
+		}
+
+		if !found {
+			// Swap remove the retired sample
+			active[a] = active[len(active) - 1]
+			active = active[:len(active) - 1]
+		}
+	}
+
+	return points
+}
+
+// Places up to num pegs inside of the peg bounds using the level's peg layout. Fewer are placed if the bounds are too small to fit them all
+func (g *Game) PlacePegs(num int) error {
+	layout, ok := pegLayouts[g.pegLayout]
+	if !ok {
+		layout = RandomLayout
+	}
+
+	points := layout(g.pegBounds, g.pegSpacing, num)
+	for _, p := range points {
+		err := g.AddPeg(PegKindForLevel(g.difficulty), p[0], p[1])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"math"
+
+	"github.com/jakecoffman/cp"
+)
+
+const (
+	CollisionPackage cp.CollisionType = iota + 1
+	CollisionPeg
+	CollisionBumper
+	CollisionBreakable
+)
+
+type PegKind uint8
+const (
+	PegStatic PegKind = iota
+	PegBumper // A very bouncy peg that kicks packages away from it
+	PegLinear // A kinematic peg sliding back and forth
+	PegOrbit // A kinematic peg moving in a circle
+	PegBar // A kinematic paddle spinning around its center
+	PegBreakable // A peg that disappears after a few hits
+)
+
+// Describes how each kind of peg looks
+type pegType struct {
+	animation string // The aseprite file the peg is exported from. Its idle tag is the peg's sprite and its hit tag plays when a package hits it
+}
+
+var pegTypes = map[PegKind]pegType{
+	PegStatic: {"peg"},
+	PegBumper: {"peg-bumper"},
+	PegLinear: {"peg-linear"},
+	PegOrbit: {"peg-orbit"},
+	PegBar: {"peg-bar"},
+	PegBreakable: {"peg-breakable"},
+}
+
+var pegKindBands = []difficultyBand[PegKind]{
+	{10, NewRngTable(
+		NewRngItem(40, PegStatic),
+		NewRngItem(10, PegBumper),
+		NewRngItem(10, PegLinear),
+		NewRngItem(10, PegOrbit),
+		NewRngItem(5, PegBar),
+		NewRngItem(10, PegBreakable),
+	)},
+	{5, NewRngTable(
+		NewRngItem(70, PegStatic),
+		NewRngItem(10, PegBumper),
+		NewRngItem(5, PegLinear),
+		NewRngItem(10, PegBreakable),
+	)},
+	{0, NewRngTable(
+		NewRngItem(1, PegStatic),
+	)},
+}
+
+const (
+	bumperElasticity = 1.5
+	bumperKick = 40.0 // Extra speed added to a package along the contact normal when it hits a bumper
+	breakableHits = 3
+	moverSpeed = 1.0 // Radians per second of the moving peg cycles
+	barSpeed = 0.75 // Radians per second that bars spin at
+	barThickness = 16.0
+)
+
+// Runtime state for a peg. Only the moving and breakable kinds make use of most of this
+type Peg struct {
+	kind PegKind
+	body *cp.Body
+	shape *cp.Shape
+	origin cp.Vector
+	extent float64 // How far a moving peg travels from its origin
+	phase float64
+	hitsLeft int
+}
+
+// Returns a random peg kind that is allowed at a difficulty level
+func PegKindForLevel(difficulty int) PegKind {
+	return rollBand(pegKindBands, difficulty, PegStatic)
+}
+
+// Registers the collision callbacks that give bumpers and breakable pegs their behavior
+func (g *Game) AddPegCollisionHandlers() {
+	addBumperHandler(g.space)
+
+	breakable := g.space.NewCollisionHandler(CollisionBreakable, CollisionPackage)
+	breakable.BeginFunc = func(arb *cp.Arbiter, space *cp.Space, userData interface{}) bool {
+		pegBody, _ := arb.Bodies()
+		peg := pegBody.UserData.(Sprite).peg
+		if peg == nil || peg.hitsLeft <= 0 { return true }
+
+		peg.hitsLeft--
+		if peg.hitsLeft <= 0 {
+			// Shapes can't be removed while the space is stepping
+			space.AddPostStepCallback(func(space *cp.Space, key, data interface{}) {
+				g.RemovePeg(peg)
+			}, peg, nil)
+		}
+		return true
+	}
+}
+
+func addBumperHandler(space *cp.Space) {
+	bumper := space.NewCollisionHandler(CollisionBumper, CollisionPackage)
+	bumper.PostSolveFunc = func(arb *cp.Arbiter, space *cp.Space, userData interface{}) {
+		if !arb.IsFirstContact() { return }
+
+		_, pkg := arb.Bodies()
+		// The normal points from the bumper into the package
+		kick := arb.Normal().Mult(bumperKick * pkg.Mass())
+		pkg.ApplyImpulseAtWorldPoint(kick, pkg.Position())
+	}
+}
+
+// Returns where a moving peg should be at time t. Pegs that don't move always stay at their origin
+func (p *Peg) PathPosition(t float64) cp.Vector {
+	theta := moverSpeed * t + p.phase
+	switch p.kind {
+	case PegLinear:
+		return p.origin.Add(cp.Vector{p.extent * math.Sin(theta), 0})
+	case PegOrbit:
+		return p.origin.Add(cp.Vector{p.extent * math.Cos(theta), p.extent * math.Sin(theta)})
+	}
+	return p.origin
+}
+
+// Drives the kinematic pegs to where they should be after the next physics step of length dt
+func (g *Game) UpdatePegs(dt float64) {
+	g.levelTime += dt
+
+	for _, peg := range g.pegs {
+		if peg.kind != PegLinear && peg.kind != PegOrbit { continue }
+
+		// Kinematic bodies are moved with velocity so that the solver can push packages along with them
+		target := peg.PathPosition(g.levelTime)
+		peg.body.SetVelocityVector(target.Sub(peg.body.Position()).Mult(1 / dt))
+	}
+}
+
+func (g *Game) RemovePeg(peg *Peg) {
+	for i := range g.pegs {
+		if g.pegs[i] == peg {
+			g.pegs = append(g.pegs[:i], g.pegs[i+1:]...)
+			break
+		}
+	}
+
+	g.space.RemoveShape(peg.shape)
+	g.space.RemoveBody(peg.body)
+}
+
+func makeBumper(sprite Sprite, x, y float64) *cp.Shape {
+	shape := makePeg(sprite, x, y)
+	setCollisionType(shape, CollisionBumper)
+	shape.SetElasticity(bumperElasticity)
+	return shape
+}
+
+func makeMovingPeg(sprite Sprite, x, y float64) *cp.Shape {
+	body := cp.NewKinematicBody()
+	body.SetPosition(cp.Vector{x, y})
+
+	radius := sprite.sprite.Bounds().W()/2
+
+	body.UserData = sprite
+
+	shape := cp.NewCircle(body, radius, cp.Vector{})
+	setCollisionType(shape, CollisionPeg)
+	shape.SetElasticity(0.5)
+	shape.SetDensity(1)
+	shape.SetFriction(0.2)
+
+	return shape
+}
+
+func makeBar(sprite Sprite, x, y, length float64) *cp.Shape {
+	body := cp.NewKinematicBody()
+	body.SetPosition(cp.Vector{x, y})
+	body.SetAngularVelocity(barSpeed)
+
+	body.UserData = sprite
+
+	shape := cp.NewBox(body, length, barThickness, 0)
+	setCollisionType(shape, CollisionPeg)
+	shape.SetElasticity(0.3)
+	shape.SetDensity(1)
+	shape.SetFriction(0.5)
+
+	return shape
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/unitoftime/glitch"
+)
+
+const (
+	perfHistory = 240 // The number of frames shown in the frame time graph
+	perfGraphWidth = 480.0
+	perfGraphHeight = 150.0
+	perfGraphMaxTime = 1.0 / 20 // The frame time at the top of the graph, in seconds
+	perfTargetTime = 1.0 / 60
+)
+
+var (
+	perfBackgroundColor = glitch.RGBA{0, 0, 0, 0.5}
+	perfFastColor = glitch.FromUint8(0x99, 0xe5, 0x50, 0xff)
+	perfSlowColor = glitch.FromUint8(0xfa, 0xcb, 0x3e, 0xff)
+	perfDroppedColor = glitch.FromUint8(0xd9, 0x57, 0x63, 0xff)
+	perfTargetColor = glitch.RGBA{1, 1, 1, 0.5}
+)
+
+// What one frame cost. Times are in seconds
+type FrameStats struct {
+	frameTime float64
+	physicsTime float64
+	bodies, shapes int
+	drawCalls int
+}
+
+// An overlay with the frame rate, a graph of recent frame times and what the last frame cost
+type PerfHUD struct {
+	enabled bool
+	history [perfHistory]float64 // Frame times, used as a ring buffer
+	next int
+	last FrameStats
+
+	geom *glitch.GeomDraw
+	mesh *glitch.Mesh // Rebuilt every frame
+	text *glitch.Text
+}
+
+func NewPerfHUD(atlas *glitch.Atlas) *PerfHUD {
+	return &PerfHUD{
+		geom: glitch.NewGeomDraw(),
+		mesh: glitch.NewMesh(),
+		text: atlas.Text(""),
+	}
+}
+
+func (h *PerfHUD) Toggle() {
+	h.enabled = !h.enabled
+}
+
+// Switches the text to another atlas, such as when the font is reloaded
+func (h *PerfHUD) SetAtlas(atlas *glitch.Atlas) {
+	h.text = atlas.Text("")
+}
+
+// Records the frame. This happens even while the overlay is hidden, so that the graph is already full when it is opened
+func (h *PerfHUD) Record(stats FrameStats) {
+	h.last = stats
+	h.history[h.next] = stats.frameTime
+	h.next = (h.next + 1) % perfHistory
+}
+
+// The average frame rate over the graph's history
+func (h *PerfHUD) FPS() float64 {
+	total := 0.0
+	frames := 0
+	for _, t := range h.history {
+		if t <= 0 { continue }
+		total += t
+		frames++
+	}
+	if total <= 0 { return 0 }
+	return float64(frames) / total
+}
+
+// Draws the overlay into the top left of screen
+func (h *PerfHUD) Draw(pass *glitch.RenderPass, screen glitch.Rect) {
+	if !h.enabled { return }
+
+	area := screen.Unpad(glitch.R(25, 25, 25, 25))
+	graph := glitch.R(0, 0, perfGraphWidth, perfGraphHeight)
+	graph = area.Anchor(graph, AnchorTopLeft)
+
+	h.mesh.Clear()
+	h.geom.SetColor(perfBackgroundColor)
+	h.mesh.Append(h.geom.FillRect(graph))
+
+	// Oldest frames on the left
+	barWidth := perfGraphWidth / perfHistory
+	for i := 0; i < perfHistory; i++ {
+		t := h.history[(h.next + i) % perfHistory]
+		if t <= 0 { continue }
+
+		color := perfFastColor
+		if t > 2 * perfTargetTime {
+			color = perfDroppedColor
+		} else if t > 1.1 * perfTargetTime {
+			color = perfSlowColor
+		}
+		h.geom.SetColor(color)
+
+		height := perfGraphHeight * math.Min(1, t / perfGraphMaxTime)
+		x := graph.Min[0] + float64(i) * barWidth
+		h.mesh.Append(h.geom.FillRect(glitch.R(x, graph.Min[1], x + barWidth, graph.Min[1] + height)))
+	}
+
+	h.geom.SetColor(perfTargetColor)
+	target := graph.Min[1] + perfGraphHeight * perfTargetTime / perfGraphMaxTime
+	h.mesh.Append(h.geom.FillRect(glitch.R(graph.Min[0], target, graph.Max[0], target + 1)))
+	h.mesh.Draw(pass, glitch.Mat4Ident)
+
+	h.text.Set(fmt.Sprintf("FPS: %.0f\nFrame: %.1f ms\nPhysics: %.2f ms\nBodies: %d\nShapes: %d\nDraw Calls: %d",
+		h.FPS(), h.last.frameTime * 1000, h.last.physicsTime * 1000, h.last.bodies, h.last.shapes, h.last.drawCalls))
+	bounds := h.text.Bounds().Scaled(0.5)
+	below := glitch.R(area.Min[0], area.Min[1], area.Max[0], graph.Min[1] - 10)
+	h.text.RectDrawColorMask(pass, below.Anchor(bounds, AnchorTopLeft), glitch.White)
+}
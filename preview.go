@@ -0,0 +1,87 @@
+package main
+
+import (
+	"github.com/jakecoffman/cp"
+
+	"github.com/unitoftime/glitch"
+)
+
+var previewColor = glitch.FromUint8(0xff, 0xff, 0xff, 0x80)
+
+// The aim assist gets shorter as the levels get harder
+func previewSteps(difficulty int) int {
+	steps := 40 - 2 * difficulty
+	if steps < 8 {
+		steps = 8
+	}
+	return steps
+}
+
+// The copy of the space that drops are predicted in. Copying every body and shape each frame is slow, so the copy is only rebuilt when bodies are added or removed, and otherwise moved back into place before each prediction
+type previewSpace struct {
+	source *cp.Space // The space that was copied, which changes when a level is reset
+	space *cp.Space
+	bodies map[*cp.Body]*cp.Body
+}
+
+// Returns the copy of the space in the same state as the real one
+func (g *Game) previewClone() (*cp.Space, map[*cp.Body]*cp.Body) {
+	p := &g.preview
+	if p.source == g.space && SyncClone(g.space, p.bodies) {
+		return p.space, p.bodies
+	}
+
+	p.source = g.space
+	p.space, p.bodies = CloneSpace(g.space)
+	addBumperHandler(p.space)
+	return p.space, p.bodies
+}
+
+// Simulates dropping the held package from where it is right now in a copy of the space and returns the path that it takes. The last point is where it comes to rest, or where it was when we ran out of steps
+func (g *Game) PredictDrop(steps int, dt float64) []glitch.Vec3 {
+	if g.heldShape == nil { return nil }
+
+	space, bodies := g.previewClone()
+
+	held := bodies[g.heldShape.Body()]
+	held.SetVelocity(0, -20)
+
+	path := make([]glitch.Vec3, 0, steps + 1)
+	pos := held.Position()
+	path = append(path, glitch.Vec3{pos.X, pos.Y, 0})
+
+	levelTime := g.levelTime
+	for i := 0; i < steps; i++ {
+		// Move the copied kinematic pegs the same way that UpdatePegs moves the real ones
+		levelTime += dt
+		for _, peg := range g.pegs {
+			if peg.kind != PegLinear && peg.kind != PegOrbit { continue }
+			body := bodies[peg.body]
+			target := peg.PathPosition(levelTime)
+			body.SetVelocityVector(target.Sub(body.Position()).Mult(1 / dt))
+		}
+
+		space.Step(dt)
+
+		pos = held.Position()
+		path = append(path, glitch.Vec3{pos.X, pos.Y, 0})
+
+		if held.IsSleeping() { break }
+		if pos.Y < g.levelBounds.Min[1] { break } // Fell out of the level
+	}
+
+	return path
+}
+
+func (g *Game) DrawPreview(pass *glitch.RenderPass, path []glitch.Vec3) {
+	if len(path) < 2 { return }
+
+	g.previewMesh.Clear()
+
+	geom := glitch.NewGeomDraw()
+	geom.SetColor(previewColor)
+	geom.LineStrip(g.previewMesh, path, 3)
+	geom.Circle(g.previewMesh, path[len(path) - 1], 12, 3)
+
+	g.previewMesh.Draw(pass, glitch.Mat4Ident)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+)
+
+// Writes a CPU profile, a heap profile and the time of every frame into a directory, so that performance can be compared between runs
+type Profiler struct {
+	dir string
+	cpu io.WriteCloser
+	framesFile io.WriteCloser
+	frames *csv.Writer
+	frame int
+}
+
+// Starts the CPU profile and the frame time file. Stop must be called to finish writing them
+func StartProfiler(dir string) (*Profiler, error) {
+	cpu, err := createProfileFile(dir, "cpu.pprof")
+	if err != nil {
+		return nil, err
+	}
+	err = pprof.StartCPUProfile(cpu)
+	if err != nil {
+		cpu.Close()
+		return nil, err
+	}
+
+	framesFile, err := createProfileFile(dir, "frames.csv")
+	if err != nil {
+		pprof.StopCPUProfile()
+		cpu.Close()
+		return nil, err
+	}
+	frames := csv.NewWriter(framesFile)
+	frames.Write([]string{"frame", "frame_ms", "physics_ms", "bodies", "shapes", "draw_calls"})
+
+	return &Profiler{
+		dir: dir,
+		cpu: cpu,
+		framesFile: framesFile,
+		frames: frames,
+	}, nil
+}
+
+// Adds a row to the frame time file. A nil profiler does nothing, so frames can be recorded whether or not profiling is on
+func (p *Profiler) Record(stats FrameStats) {
+	if p == nil { return }
+
+	p.frames.Write([]string{
+		strconv.Itoa(p.frame),
+		strconv.FormatFloat(stats.frameTime * 1000, 'f', 3, 64),
+		strconv.FormatFloat(stats.physicsTime * 1000, 'f', 3, 64),
+		strconv.Itoa(stats.bodies),
+		strconv.Itoa(stats.shapes),
+		strconv.Itoa(stats.drawCalls),
+	})
+	p.frame++
+}
+
+// Stops the CPU profile, writes the heap profile and closes everything. Keeps going past errors so that as much as possible gets written
+func (p *Profiler) Stop() error {
+	if p == nil { return nil }
+
+	var problems []error
+	pprof.StopCPUProfile()
+	if err := p.cpu.Close(); err != nil {
+		problems = append(problems, fmt.Errorf("cpu profile: %w", err))
+	}
+
+	p.frames.Flush()
+	if err := p.frames.Error(); err != nil {
+		problems = append(problems, fmt.Errorf("frame times: %w", err))
+	}
+	if err := p.framesFile.Close(); err != nil {
+		problems = append(problems, fmt.Errorf("frame times: %w", err))
+	}
+
+	heap, err := createProfileFile(p.dir, "heap.pprof")
+	if err != nil {
+		problems = append(problems, fmt.Errorf("heap profile: %w", err))
+	} else {
+		runtime.GC() // Brings the heap profile up to date
+		if err := pprof.WriteHeapProfile(heap); err != nil {
+			problems = append(problems, fmt.Errorf("heap profile: %w", err))
+		}
+		if err := heap.Close(); err != nil {
+			problems = append(problems, fmt.Errorf("heap profile: %w", err))
+		}
+	}
+
+	return errors.Join(problems...)
+}
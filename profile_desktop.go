@@ -0,0 +1,17 @@
+//go:build !js
+
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+func createProfileFile(dir, name string) (io.WriteCloser, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	return os.Create(filepath.Join(dir, name))
+}
@@ -0,0 +1,13 @@
+//go:build js
+
+package main
+
+import (
+	"errors"
+	"io"
+)
+
+// The performance overlay still works in the browser, but there is nowhere to write the profiles to
+func createProfileFile(dir, name string) (io.WriteCloser, error) {
+	return nil, errors.New("profiling isn't supported in the browser")
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jakecoffman/cp"
+
+	"github.com/unitoftime/glitch"
+)
+
+const (
+	scorePerPackage = 100
+	perfectBonus = 500 // Awarded when every package of a level ends up in the right bin
+)
+
+// The outcome of a single level, which is shown on the results screen
+type LevelResult struct {
+	Level int
+	Accepted, WrongBin, Lost int
+	HealthBefore, HealthAfter int
+	Score int // Points earned this level
+}
+
+// Scores the finished level and applies its health loss. Moves to the results screen, or the game over screen if the player ran out of health
+func (g *Game) EndLevel() {
+	accepted, wrongBin, lost := g.SortPackages()
+
+	score := accepted * scorePerPackage
+	if wrongBin + lost == 0 {
+		score += perfectBonus
+	}
+	score *= g.difficulty + 1 // Later levels are worth more
+
+	g.lastResult = LevelResult{
+		Level: g.difficulty,
+		Accepted: accepted,
+		WrongBin: wrongBin,
+		Lost: lost,
+		HealthBefore: g.health,
+		HealthAfter: g.health - (wrongBin + lost),
+		Score: score,
+	}
+
+	g.health = g.lastResult.HealthAfter
+	g.score += score
+
+	// Puff away every package that missed the bins
+	g.space.EachShape(func(shape *cp.Shape) {
+		if !shape.Body().UserData.(Sprite).isPackage { return }
+		if _, ok := g.PackageBin(shape); ok { return }
+
+		pos := shape.Body().Position()
+		g.particles.Emit(lostEffect, glitch.Vec2{pos.X, pos.Y}, math.Pi / 2)
+	})
+
+	if g.health <= 0 {
+		g.newRecord = g.score > g.record
+		if g.newRecord {
+			g.record = g.score
+		}
+		g.mode = "gameover"
+		return
+	}
+	g.mode = "results"
+
+	top := glitch.Vec2{g.levelBounds.Center()[0], g.levelBounds.Max[1]}
+	g.particles.Emit(confettiEffect, top, -math.Pi / 2)
+}
+
+// Moves on from the results screen to the next level
+func (g *Game) NextLevel() error {
+	g.difficulty++
+	g.mode = "game"
+	return g.ResetLevel()
+}
+
+// A summary panel drawn over the finished level, with a column of buttons underneath
+type ResultsScreen struct {
+	title string
+	banner string // Only shown when showBanner is set
+	showBanner bool
+	buttons []string
+	lines []string
+}
+
+func NewResultsScreen(title, banner string, buttons ...string) *ResultsScreen {
+	return &ResultsScreen{
+		title: title,
+		banner: banner,
+		buttons: buttons,
+	}
+}
+
+// Declares the screen. Returns the index of the button that was picked this frame, or -1
+func (r *ResultsScreen) Update(ui *UI) int {
+	lineHeight := 50.0
+	buttonHeight := 100.0
+	gap := 25.0
+
+	height := 125 + float64(len(r.lines)) * lineHeight + gap + ColumnHeight(len(r.buttons), buttonHeight, gap) + 50
+	area := Anchored(ui.Screen(), AnchorCenter, 800, height)
+	ui.Panel(area, menuShade)
+
+	if r.showBanner {
+		theta := float64(time.Now().UnixMilli()) / 1000
+		bannerOscillation := 5 * math.Sin(7 * theta)
+		banner := area.Moved(glitch.Vec2{0, bannerOscillation})
+		banner.Min[1] = area.Max[1] + 25
+		banner.Max[1] = banner.Min[1] + 100
+		ui.Label(r.banner, banner, AnchorCenter, 1, highlightColor)
+	}
+
+	column := NewColumn(area.Unpad(glitch.R(150, 25, 150, 0)), 0)
+	ui.Label(r.title, column.Next(125), AnchorCenter, 1, glitch.White)
+	for _, line := range r.lines {
+		ui.Label(line, column.Next(lineHeight), AnchorCenter, 0.5, glitch.White)
+	}
+
+	column.Next(gap)
+	column.gap = gap
+	picked := -1
+	for i, button := range r.buttons {
+		if ui.Button(button, column.Next(buttonHeight)) {
+			picked = i
+		}
+	}
+	return picked
+}
+
+// Fills in the level summary from the last finished level
+func (r *ResultsScreen) SetLevelResult(result LevelResult, total int) {
+	r.lines = []string{
+		fmt.Sprintf("Level %d", result.Level + 1),
+		fmt.Sprintf("Accepted: %d", result.Accepted),
+		fmt.Sprintf("Wrong Bin: %d", result.WrongBin),
+		fmt.Sprintf("Lost: %d", result.Lost),
+		fmt.Sprintf("Health: %d -> %d", result.HealthBefore, result.HealthAfter),
+		fmt.Sprintf("Score: +%d (Total %d)", result.Score, total),
+	}
+}
+
+// Fills in the game over summary
+func (r *ResultsScreen) SetGameOver(result LevelResult, total int, newRecord bool) {
+	r.lines = []string{
+		fmt.Sprintf("Reached Level %d", result.Level + 1),
+		fmt.Sprintf("Last Level: +%d", result.Score),
+		fmt.Sprintf("Final Score: %d", total),
+	}
+	r.showBanner = newRecord
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Player preferences that persist between runs
+type Settings struct {
+	Bindings map[string]BindingNames `json:"bindings"`
+	Volume float64 `json:"volume"`
+	Muted bool `json:"muted"`
+	ShowPreview bool `json:"showPreview"`
+
+	// Camera effects, which can be turned down or off for anyone who finds them uncomfortable
+	ScreenShake float64 `json:"screenShake"` // From 0 to 1
+	HitStop bool `json:"hitStop"`
+	CameraZoom bool `json:"cameraZoom"`
+}
+
+func DefaultSettings() Settings {
+	return Settings{
+		Bindings: DefaultBindings().Names(),
+		Volume: 0.5,
+		ShowPreview: true,
+		ScreenShake: 1,
+		HitStop: true,
+		CameraZoom: true,
+	}
+}
+
+// Loads the settings file, falling back to the defaults if there isn't one yet
+func LoadSettings() (Settings, error) {
+	settings := DefaultSettings()
+
+	dat, err := readSettingsFile()
+	if err != nil {
+		return settings, err
+	}
+	if dat == nil {
+		return settings, nil
+	}
+
+	err = json.Unmarshal(dat, &settings)
+	if err != nil {
+		return DefaultSettings(), fmt.Errorf("invalid settings file: %w", err)
+	}
+	return settings, nil
+}
+
+func SaveSettings(settings Settings) error {
+	dat, err := json.MarshalIndent(settings, "", "\t")
+	if err != nil {
+		return err
+	}
+	return writeSettingsFile(dat)
+}
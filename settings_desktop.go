@@ -0,0 +1,45 @@
+//go:build !js
+
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+func settingsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "boxlin", "settings.json"), nil
+}
+
+// Returns nil data if the settings file doesn't exist yet
+func readSettingsFile() ([]byte, error) {
+	path, err := settingsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	dat, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return dat, err
+}
+
+func writeSettingsFile(dat []byte) error {
+	path, err := settingsPath()
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, dat, 0644)
+}
@@ -0,0 +1,43 @@
+//go:build js
+
+package main
+
+import (
+	"errors"
+	"syscall/js"
+)
+
+// Browsers don't have a filesystem, so the settings file lives in local storage
+const settingsKey = "boxlin-settings"
+
+func localStorage() (js.Value, error) {
+	storage := js.Global().Get("localStorage")
+	if storage.IsUndefined() || storage.IsNull() {
+		return storage, errors.New("local storage is unavailable")
+	}
+	return storage, nil
+}
+
+// Returns nil data if nothing has been saved yet
+func readSettingsFile() ([]byte, error) {
+	storage, err := localStorage()
+	if err != nil {
+		return nil, err
+	}
+
+	item := storage.Call("getItem", settingsKey)
+	if item.IsNull() {
+		return nil, nil
+	}
+	return []byte(item.String()), nil
+}
+
+func writeSettingsFile(dat []byte) error {
+	storage, err := localStorage()
+	if err != nil {
+		return err
+	}
+
+	storage.Call("setItem", settingsKey, string(dat))
+	return nil
+}
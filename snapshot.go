@@ -0,0 +1,121 @@
+package main
+
+import (
+	"github.com/jakecoffman/cp"
+)
+
+// Chipmunk doesn't expose a shape's collision type or surface velocity, so we keep a copy in the shape's UserData so that spaces can be snapshotted
+type shapeProps struct {
+	collisionType cp.CollisionType
+	surfaceV cp.Vector
+}
+
+func getShapeProps(shape *cp.Shape) shapeProps {
+	props, _ := shape.UserData.(shapeProps)
+	return props
+}
+
+func setCollisionType(shape *cp.Shape, collisionType cp.CollisionType) {
+	props := getShapeProps(shape)
+	props.collisionType = collisionType
+	shape.UserData = props
+	shape.SetCollisionType(collisionType)
+}
+
+func setSurfaceV(shape *cp.Shape, surfaceV cp.Vector) {
+	props := getShapeProps(shape)
+	props.surfaceV = surfaceV
+	shape.UserData = props
+	shape.SetSurfaceV(surfaceV)
+}
+
+// Builds a copy of the space with the same bodies and shapes in their current state. Collision handlers and constraints are not copied. Returns the new space and a lookup from the original bodies to their copies
+func CloneSpace(space *cp.Space) (*cp.Space, map[*cp.Body]*cp.Body) {
+	clone := cp.NewSpace()
+	clone.Iterations = space.Iterations
+	clone.SleepTimeThreshold = space.SleepTimeThreshold
+	clone.SetGravity(space.Gravity())
+	clone.SetDamping(space.Damping())
+
+	bodies := make(map[*cp.Body]*cp.Body)
+	space.EachBody(func(body *cp.Body) {
+		var c *cp.Body
+		switch body.GetType() {
+		case cp.BODY_STATIC:
+			c = cp.NewStaticBody()
+		case cp.BODY_KINEMATIC:
+			c = cp.NewKinematicBody()
+		default:
+			c = cp.NewBody(body.Mass(), body.Moment())
+		}
+
+		c.SetPosition(body.Position())
+		c.SetAngle(body.Angle())
+		c.SetVelocityVector(body.Velocity())
+		c.SetAngularVelocity(body.AngularVelocity())
+		c.UserData = body.UserData
+
+		clone.AddBody(c)
+		bodies[body] = c
+	})
+
+	space.EachShape(func(shape *cp.Shape) {
+		body, ok := bodies[shape.Body()]
+		if !ok { return }
+
+		var c *cp.Shape
+		switch class := shape.Class.(type) {
+		case *cp.Circle:
+			// A circle's center of gravity is its offset from the body
+			c = cp.NewCircle(body, class.Radius(), shape.CenterOfGravity())
+		case *cp.PolyShape:
+			verts := make([]cp.Vector, class.Count())
+			for i := range verts {
+				verts[i] = class.Vert(i)
+			}
+			c = cp.NewPolyShapeRaw(body, len(verts), verts, class.Radius())
+		case *cp.Segment:
+			c = cp.NewSegment(body, class.A(), class.B(), class.Radius())
+		default:
+			return
+		}
+
+		props := getShapeProps(shape)
+		setCollisionType(c, props.collisionType)
+		setSurfaceV(c, props.surfaceV)
+		c.SetSensor(shape.Sensor())
+		c.SetElasticity(shape.Elasticity())
+		c.SetFriction(shape.Friction())
+		c.SetFilter(shape.Filter)
+		if body.GetType() == cp.BODY_DYNAMIC {
+			c.SetDensity(shape.Density())
+		}
+
+		clone.AddShape(c)
+	})
+
+	return clone, bodies
+}
+
+// Moves the bodies of a copy made by CloneSpace back to where their originals are now, so that the copy can be stepped again without rebuilding it. Returns false if bodies were added to or removed from the space since it was copied, in which case the copy has to be rebuilt
+func SyncClone(space *cp.Space, bodies map[*cp.Body]*cp.Body) bool {
+	count := 0
+	synced := true
+	space.EachBody(func(body *cp.Body) {
+		count++
+		c, ok := bodies[body]
+		if !ok {
+			synced = false
+			return
+		}
+		// Static bodies never move, and moving them would make chipmunk reindex their shapes
+		if body.GetType() == cp.BODY_STATIC { return }
+
+		c.SetPosition(body.Position())
+		c.SetAngle(body.Angle())
+		// Setting the velocity also wakes up bodies that fell asleep during the last prediction
+		c.SetVelocityVector(body.Velocity())
+		c.SetAngularVelocity(body.AngularVelocity())
+	})
+	return synced && count == len(bodies)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"github.com/unitoftime/glitch"
+)
+
+// A single touch point. Multitouch isn't needed for anything yet, so only the first finger is tracked
+type TouchState struct {
+	Active bool // A finger is currently down
+	Started, Ended bool // The finger went down or was lifted since the last frame
+	Pos glitch.Vec2 // In framebuffer pixels, the same as the mouse position
+}
+
+// A large tappable button for touch screens
+type TouchButton struct {
+	rect glitch.Rect
+	label string
+	text *glitch.Text
+}
+
+func NewTouchButton(atlas *glitch.Atlas, label string) *TouchButton {
+	return &TouchButton{
+		label: label,
+		text: atlas.Text(label),
+	}
+}
+
+// Rebuilds the label with a different font
+func (b *TouchButton) SetAtlas(atlas *glitch.Atlas) {
+	b.text = atlas.Text(b.label)
+}
+
+func (b *TouchButton) SetRect(rect glitch.Rect) {
+	b.rect = rect
+}
+
+func (b *TouchButton) Contains(pos glitch.Vec3) bool {
+	return b.rect.Contains(pos[0], pos[1])
+}
+
+func (b *TouchButton) Draw(pass *glitch.RenderPass, panel *glitch.NinePanelSprite) {
+	b.DrawColorMask(pass, panel, glitch.White)
+}
+
+// Draws the button with its background tinted, which is used to show the selected button in menus
+func (b *TouchButton) DrawColorMask(pass *glitch.RenderPass, panel *glitch.NinePanelSprite, color glitch.RGBA) {
+	panel.RectDrawColorMask(pass, b.rect, color)
+
+	// Center the label in the button
+	textBounds := b.text.Bounds()
+	x := b.rect.Center()[0] - textBounds.W()/2
+	y := b.rect.Center()[1] - textBounds.H()/2
+	b.text.DrawRect(pass, glitch.R(x, y, x + textBounds.W(), y + textBounds.H()), glitch.White)
+}
+
+// Tracks a touch from when it starts until it ends, so a touch that started on a button only ever triggers that button and never aims or drops
+type TouchControls struct {
+	buttons []*TouchButton
+	pressed *TouchButton // The button the current touch started on, if any
+}
+
+func NewTouchControls(buttons ...*TouchButton) *TouchControls {
+	return &TouchControls{
+		buttons: buttons,
+	}
+}
+
+// Returns the button that was tapped this frame, or nil, and whether this frame's touch belongs to a button at all. The touch position must already be in world coordinates
+func (t *TouchControls) Update(touch TouchState, pos glitch.Vec3) (*TouchButton, bool) {
+	if touch.Started {
+		t.pressed = nil
+		for _, b := range t.buttons {
+			if b.Contains(pos) {
+				t.pressed = b
+				break
+			}
+		}
+	}
+
+	pressed := t.pressed
+	if touch.Ended {
+		t.pressed = nil
+		if pressed != nil && pressed.Contains(pos) {
+			return pressed, true
+		}
+	}
+	return nil, pressed != nil
+}
@@ -0,0 +1,12 @@
+//go:build !js
+
+package main
+
+import (
+	"github.com/unitoftime/glitch"
+)
+
+// Desktop builds only get touch through the os mouse emulation
+func pollTouch(win *glitch.Window) TouchState {
+	return TouchState{}
+}
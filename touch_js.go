@@ -0,0 +1,80 @@
+//go:build js
+
+package main
+
+import (
+	"sync"
+	"syscall/js"
+
+	"github.com/unitoftime/glitch"
+)
+
+// Touch events arrive from the browser between frames, so they get accumulated here until the next poll
+var touchEvents struct {
+	sync.Mutex
+	registered bool
+	active bool
+	pos glitch.Vec2 // In css pixels, with y pointing down
+	started, ended bool
+}
+
+func registerTouchHandlers() {
+	handler := js.FuncOf(func(this js.Value, args []js.Value) any {
+		event := args[0]
+		// Stops the browser from also sending emulated mouse events, which would count every tap as a click
+		event.Call("preventDefault")
+
+		touchEvents.Lock()
+		defer touchEvents.Unlock()
+
+		touches := event.Get("touches")
+		if touches.Length() > 0 {
+			t := touches.Index(0)
+			touchEvents.pos = glitch.Vec2{t.Get("clientX").Float(), t.Get("clientY").Float()}
+			if !touchEvents.active {
+				touchEvents.started = true
+			}
+			touchEvents.active = true
+		} else {
+			if touchEvents.active {
+				touchEvents.ended = true
+			}
+			touchEvents.active = false
+		}
+		return nil
+	})
+
+	options := js.Global().Get("Object").New()
+	options.Set("passive", false)
+
+	document := js.Global().Get("document")
+	for _, name := range []string{"touchstart", "touchmove", "touchend", "touchcancel"} {
+		document.Call("addEventListener", name, handler, options)
+	}
+}
+
+func pollTouch(win *glitch.Window) TouchState {
+	touchEvents.Lock()
+	defer touchEvents.Unlock()
+
+	if !touchEvents.registered {
+		registerTouchHandlers()
+		touchEvents.registered = true
+	}
+
+	// Convert to framebuffer pixels with y pointing up, the same as the mouse position
+	scale := js.Global().Get("devicePixelRatio").Float()
+	state := TouchState{
+		Active: touchEvents.active,
+		Started: touchEvents.started,
+		Ended: touchEvents.ended,
+		Pos: glitch.Vec2{
+			touchEvents.pos[0] * scale,
+			win.Bounds().H() - touchEvents.pos[1] * scale,
+		},
+	}
+
+	touchEvents.started = false
+	touchEvents.ended = false
+	return state
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/unitoftime/glitch"
+)
+
+// Common anchors for placing things inside a rect
+var (
+	AnchorCenter = glitch.Vec2{0.5, 0.5}
+	AnchorTop = glitch.Vec2{0.5, 1}
+	AnchorBottom = glitch.Vec2{0.5, 0}
+	AnchorLeft = glitch.Vec2{0, 0.5}
+	AnchorRight = glitch.Vec2{1, 0.5}
+	AnchorBottomLeft = glitch.Vec2{0, 0}
+	AnchorBottomRight = glitch.Vec2{1, 0}
+	AnchorTopLeft = glitch.Vec2{0, 1}
+	AnchorTopRight = glitch.Vec2{1, 1}
+)
+
+var menuShade = glitch.FromUint8(0x40, 0x40, 0x40, 0xff)
+
+// An immediate mode UI. Widgets are declared every frame in the order they should be navigated, and each call both handles the widget's input and queues it to be drawn. The UI has its own render pass so that it always draws over the game
+type UI struct {
+	win *glitch.Window
+	input *Input
+	atlas *glitch.Atlas
+	panel *glitch.NinePanelSprite
+	pass *glitch.RenderPass
+
+	screen glitch.Rect // The screen in world coordinates, for anchoring
+	mousePos glitch.Vec3
+	touch TouchState
+	touchPos glitch.Vec3
+
+	focus int // The widget that the keyboard and gamepad act on, in declaration order
+	beginFocus int // The focus before this frame's navigation moved it
+	count int // The number of focusable widgets declared so far this frame
+	lastCount int
+	pressed int // The widget that the current mouse press or touch started on, or -1
+
+	// Navigation input, gathered once per frame
+	up, down, left, right, confirm bool
+
+	texts []*glitch.Text // Reused between frames so that labels don't allocate
+	textIndex int
+}
+
+func NewUI(win *glitch.Window, input *Input, atlas *glitch.Atlas, panel *glitch.NinePanelSprite, shader *glitch.Shader) *UI {
+	return &UI{
+		win: win,
+		input: input,
+		atlas: atlas,
+		panel: panel,
+		pass: glitch.NewRenderPass(shader),
+		pressed: -1,
+	}
+}
+
+// Must be called once per frame before any widgets are declared. The positions must already be in world coordinates
+func (u *UI) Begin(screen glitch.Rect, mousePos glitch.Vec3, touch TouchState, touchPos glitch.Vec3) {
+	u.pass.Clear()
+	u.textIndex = 0
+
+	u.screen = screen
+	u.mousePos = mousePos
+	u.touch = touch
+	u.touchPos = touchPos
+
+	u.lastCount = u.count
+	u.count = 0
+
+	// Navigation uses fixed keys alongside the confirm action so that a bad binding can't lock the player out of the menus
+	u.up = u.win.JustPressed(glitch.KeyUp) || u.input.JustPressedButton(GamepadDpadUp)
+	u.down = u.win.JustPressed(glitch.KeyDown) || u.input.JustPressedButton(GamepadDpadDown)
+	u.left = u.win.JustPressed(glitch.KeyLeft) || u.input.JustPressedButton(GamepadDpadLeft)
+	u.right = u.win.JustPressed(glitch.KeyRight) || u.input.JustPressedButton(GamepadDpadRight)
+	u.confirm = u.win.JustPressed(glitch.KeyEnter) || u.input.JustPressed(ActionConfirm)
+
+	u.beginFocus = u.focus
+	if u.lastCount > 0 {
+		if u.up {
+			u.focus = (u.focus + u.lastCount - 1) % u.lastCount
+		}
+		if u.down {
+			u.focus = (u.focus + 1) % u.lastCount
+		}
+		if u.focus >= u.lastCount {
+			u.focus = u.lastCount - 1
+		}
+	}
+
+	// The touch's end is still reported on the frame after it lifts, so hold on to the pressed widget until then
+	if !u.win.Pressed(glitch.MouseButtonLeft) && !u.touch.Active && !u.touch.Ended {
+		u.pressed = -1
+	}
+}
+
+// Switches to a different font. The pooled labels were built from the old one, so they are thrown away
+func (u *UI) SetAtlas(atlas *glitch.Atlas) {
+	u.atlas = atlas
+	u.texts = nil
+}
+
+// Stops the keyboard and gamepad from navigating or activating widgets this frame, for screens that are reading those keys themselves. The mouse and touch still work. Must be called before any widgets are declared
+func (u *UI) IgnoreNavigation() {
+	u.focus = u.beginFocus
+	u.up, u.down, u.left, u.right, u.confirm = false, false, false, false, false
+}
+
+// Moves focus back to the first widget. Should be called when switching between screens
+func (u *UI) ResetFocus() {
+	u.focus = 0
+	u.pressed = -1
+}
+
+func (u *UI) Screen() glitch.Rect {
+	return u.screen
+}
+
+func (u *UI) Draw(target glitch.Target, camera *glitch.CameraOrtho) {
+	u.pass.SetUniform("projection", camera.Projection)
+	u.pass.SetUniform("view", camera.View)
+	u.pass.Draw(target)
+}
+
+// The number of draw calls the UI made last frame
+func (u *UI) DrawCalls() int {
+	return u.pass.Stats().DrawCalls
+}
+
+func (u *UI) getText(str string) *glitch.Text {
+	if u.textIndex >= len(u.texts) {
+		u.texts = append(u.texts, u.atlas.Text(str))
+	}
+	text := u.texts[u.textIndex]
+	u.textIndex++
+	text.Set(str)
+	return text
+}
+
+// The position of whichever pointer is currently in use
+func (u *UI) pointer() glitch.Vec3 {
+	if u.touch.Active || u.touch.Ended {
+		return u.touchPos
+	}
+	return u.mousePos
+}
+
+// Registers a focusable widget. Returns its id and whether it was activated this frame by a click, tap or the confirm key
+func (u *UI) interact(rect glitch.Rect) (int, bool) {
+	id := u.count
+	u.count++
+
+	activated := false
+	hovered := rect.Contains(u.mousePos[0], u.mousePos[1])
+	if hovered && u.input.MouseMoved() {
+		u.focus = id
+	}
+	if hovered && u.win.JustPressed(glitch.MouseButtonLeft) {
+		u.focus = id
+		u.pressed = id
+		activated = true
+	}
+
+	if u.touch.Started && rect.Contains(u.touchPos[0], u.touchPos[1]) {
+		u.focus = id
+		u.pressed = id
+	}
+	if u.touch.Ended && u.pressed == id && rect.Contains(u.touchPos[0], u.touchPos[1]) {
+		activated = true
+	}
+
+	if u.focus == id && u.confirm {
+		activated = true
+	}
+	return id, activated
+}
+
+func (u *UI) shade(id int) glitch.RGBA {
+	if id == u.focus {
+		return highlightColor
+	}
+	return menuShade
+}
+
+func (u *UI) Panel(rect glitch.Rect, color glitch.RGBA) {
+	u.panel.RectDrawColorMask(u.pass, rect, color)
+}
+
+// Draws the text at the given scale, placed inside rect by the anchor
+func (u *UI) Label(str string, rect glitch.Rect, anchor glitch.Vec2, scale float64, color glitch.RGBA) {
+	text := u.getText(str)
+	r := rect.Anchor(text.Bounds().Scaled(scale), anchor)
+	text.RectDrawColorMask(u.pass, r, color)
+}
+
+// Returns true if the button was pressed this frame
+func (u *UI) Button(label string, rect glitch.Rect) bool {
+	id, activated := u.interact(rect)
+	u.Panel(rect, u.shade(id))
+	u.Label(label, rect, AnchorCenter, 1, glitch.White)
+	return activated
+}
+
+// A button that flips value. Returns true if the value changed this frame
+func (u *UI) Toggle(label string, value *bool, rect glitch.Rect) bool {
+	id, activated := u.interact(rect)
+	if id == u.focus && (u.left || u.right) {
+		activated = true
+	}
+	if activated {
+		*value = !*value
+	}
+
+	state := "Off"
+	if *value {
+		state = "On"
+	}
+
+	inner := rect.Unpad(glitch.R(25, 0, 25, 0))
+	u.Panel(rect, u.shade(id))
+	u.Label(label, inner, AnchorLeft, 0.75, glitch.White)
+	u.Label(state, inner, AnchorRight, 0.75, glitch.White)
+	return activated
+}
+
+// A horizontal slider that holds value between min and max. It can be dragged or stepped with left and right. Returns true if the value changed this frame
+func (u *UI) Slider(label string, value *float64, min, max float64, rect glitch.Rect) bool {
+	id, _ := u.interact(rect)
+
+	inner := rect.Unpad(glitch.R(25, 0, 25, 0))
+	track := inner
+	track.CutLeft(inner.W() / 2)
+	track = track.SliceHorizontal(20)
+
+	last := *value
+	step := (max - min) / 10
+	if id == u.focus {
+		if u.left {
+			*value -= step
+		}
+		if u.right {
+			*value += step
+		}
+	}
+	if u.pressed == id {
+		pos := u.pointer()
+		*value = min + (max - min) * (pos[0] - track.Min[0]) / track.W()
+	}
+	if *value < min {
+		*value = min
+	} else if *value > max {
+		*value = max
+	}
+
+	u.Panel(rect, u.shade(id))
+	u.Label(label, inner, AnchorLeft, 0.75, glitch.White)
+	u.Panel(track, menuShade)
+
+	knobX := track.Min[0] + track.W() * (*value - min) / (max - min)
+	knob := glitch.R(knobX - 15, track.Min[1] - 20, knobX + 15, track.Max[1] + 20)
+	u.Panel(knob, glitch.White)
+
+	percent := track
+	percent.Max[0] = track.Min[0] - 25
+	percent.Min[0] = percent.Max[0] - 100
+	u.Label(fmt.Sprintf("%d%%", int(100 * (*value - min) / (max - min) + 0.5)), percent, AnchorRight, 0.5, glitch.White)
+
+	return *value != last
+}
+
+// A column of rows where one can be selected. Each row is its own focusable widget. Returns the row that was activated this frame, or -1. Activating a row also selects it
+func (u *UI) List(items []string, selected *int, rect glitch.Rect, rowHeight float64) int {
+	picked := -1
+	column := NewColumn(rect, 0)
+	for i, item := range items {
+		row := column.Next(rowHeight)
+		id, activated := u.interact(row)
+		if activated {
+			*selected = i
+			picked = i
+		}
+
+		color := glitch.White
+		if i == *selected {
+			color = highlightColor
+		}
+		if id == u.focus {
+			u.Panel(row, menuShade)
+		}
+		u.Label(item, row.Unpad(glitch.R(25, 0, 25, 0)), AnchorLeft, 0.5, color)
+	}
+	return picked
+}
+
+// Places a rect of the given size inside parent using the anchor
+func Anchored(parent glitch.Rect, anchor glitch.Vec2, w, h float64) glitch.Rect {
+	return parent.Anchor(glitch.R(0, 0, w, h), anchor)
+}
+
+// Hands out rects from the top of an area downwards, leaving a gap between each one
+type Column struct {
+	rect glitch.Rect
+	gap float64
+}
+
+func NewColumn(rect glitch.Rect, gap float64) *Column {
+	return &Column{
+		rect: rect,
+		gap: gap,
+	}
+}
+
+func (c *Column) Next(height float64) glitch.Rect {
+	next := c.rect.CutTop(height)
+	c.rect.CutTop(c.gap)
+	return next
+}
+
+// Returns the height needed to fit count rows with gaps between them
+func ColumnHeight(count int, height, gap float64) float64 {
+	if count <= 0 { return 0 }
+	return float64(count) * height + float64(count - 1) * gap
+}
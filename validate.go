@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io/fs"
+	"path"
+	"sort"
+
+	"github.com/hajimehoshi/go-mp3"
+
+	"github.com/unitoftime/flow/asset"
+	"github.com/unitoftime/packer"
+)
+
+// Checks that every sprite, sound and font that the game or its data files refer to actually exists, so that a typo is caught up front rather than crashing partway through a level. Only the files are read, so no window is needed. All of the problems are returned together
+func ValidateAssets(fsys fs.FS) error {
+	load := asset.NewLoad(fsys)
+	var problems []error
+	fail := func(format string, args ...any) {
+		problems = append(problems, fmt.Errorf(format, args...))
+	}
+
+	// Spritesheet
+	sprites := make(map[string]bool)
+	sheet := packer.SerializedSpritesheet{}
+	err := load.Json(spritesheetPath, &sheet)
+	if err != nil {
+		fail("spritesheet: %w", err)
+	} else {
+		imagePath := path.Join(path.Dir(spritesheetPath), sheet.ImageName)
+		img, err := load.Image(imagePath)
+		if err != nil {
+			fail("spritesheet image %s: %w", imagePath, err)
+		}
+		for _, name := range sortedKeys(sheet.Frames) {
+			sprites[name] = true
+			frame := sheet.Frames[name]
+
+			if img == nil { continue }
+			rect := image.Rect(int(frame.Frame.X), int(frame.Frame.Y), int(frame.Frame.X + frame.Frame.W), int(frame.Frame.Y + frame.Frame.H))
+			if !rect.In(img.Bounds()) {
+				fail("spritesheet: sprite %s at %v is outside of the %v image", name, rect, img.Bounds().Size())
+			}
+		}
+	}
+	checkSprite := func(source, name string) {
+		if len(sprites) == 0 { return } // Already reported that the spritesheet is missing
+		if !sprites[name] {
+			fail("%s: missing sprite %q", source, name)
+		}
+	}
+
+	// Sprites that are named in code
+	for _, item := range packageTable.Items {
+		checkSprite("package table", item.Item)
+		if _, ok := packageCategories[item.Item]; !ok {
+			fail("package table: %q has no category", item.Item)
+		}
+	}
+	for _, pkg := range sortedKeys(packageCategories) {
+		checkSprite("package categories", pkg)
+	}
+	for _, category := range sortedKeys(categoryIcons) {
+		checkSprite("category icons", categoryIcons[category])
+	}
+	checkSprite("walls", wallSprite)
+	checkSprite("background", backgroundSprite)
+
+	// Animations
+	animations, err := LoadAnimations(load, animationsPath)
+	if err != nil {
+		fail("animations: %w", err)
+	} else {
+		// The tags that the game plays, by the animation they are played on
+		checkTag := func(where, name, tag string) {
+			anim, ok := animations[name]
+			if !ok {
+				fail("%s: missing animation %s", where, name)
+				return
+			}
+			for _, t := range anim.Tags {
+				if t.Name == tag { return }
+			}
+			fail("%s: animation %s has no %q tag", where, name, tag)
+		}
+		for kind := PegStatic; kind <= PegBreakable; kind++ {
+			pegType, ok := pegTypes[kind]
+			if !ok {
+				fail("peg types: no type for peg kind %d", kind)
+				continue
+			}
+			checkTag("peg types", pegType.animation, "idle")
+			checkTag("peg types", pegType.animation, "hit")
+		}
+		checkTag("packages", packageAnimationName, "idle")
+		checkTag("packages", packageAnimationName, "land")
+		checkTag("packing line", packingLineAnimationName, "run")
+		for _, name := range sortedKeys(animations) {
+			anim := animations[name]
+			for _, frame := range anim.Frames {
+				checkSprite("animation " + name, frame.Sprite)
+			}
+			for _, tag := range anim.Tags {
+				if tag.From < 0 || tag.To >= len(anim.Frames) || tag.From > tag.To {
+					fail("animation %s: tag %q covers frames %d to %d, but there are %d frames", name, tag.Name, tag.From, tag.To, len(anim.Frames))
+				}
+			}
+		}
+	}
+
+	// Levels
+	levels, err := LoadLevels(load, levelsPath)
+	if err != nil {
+		fail("levels: %w", err)
+	}
+	for _, number := range sortedKeys(levels) {
+		level := levels[number]
+		if _, ok := pegLayouts[level.Layout]; level.Layout != "" && !ok {
+			fail("level %d: unknown peg layout %q", number, level.Layout)
+		}
+		for _, bin := range level.Bins {
+			for _, category := range bin.Categories {
+				if _, ok := categoryIcons[category]; !ok {
+					fail("level %d: unknown bin category %q", number, category)
+				}
+			}
+		}
+		for i, conveyor := range level.Conveyors {
+			for _, v := range conveyor.Rect {
+				if v < 0 || v > 1 {
+					fail("level %d: conveyor %d rect %v must be fractions between 0 and 1", number, i, conveyor.Rect)
+					break
+				}
+			}
+		}
+	}
+
+	// Font
+	_, err = load.Font(fontPath, 64)
+	if err != nil {
+		fail("font %s: %w", fontPath, err)
+	}
+
+	// Audio
+	file, err := load.Open(musicPath)
+	if err != nil {
+		fail("music %s: %w", musicPath, err)
+	} else {
+		_, err = mp3.NewDecoder(file)
+		if err != nil {
+			fail("music %s: %w", musicPath, err)
+		}
+		file.Close()
+	}
+
+	return errors.Join(problems...)
+}
+
+// Returns the keys of the map in order, so that problems are always reported in the same order
+func sortedKeys[K string | int, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}